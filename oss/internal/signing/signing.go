@@ -0,0 +1,36 @@
+// Package signing holds the signature details shared by the oss client
+// and the ossfake server so that they can't drift apart.
+package signing
+
+// SubResources are the query parameters signed in the canonical resource
+// of the signature version 1
+var SubResources = map[string]bool{
+	"acl":                          true,
+	"uploads":                      true,
+	"location":                     true,
+	"cors":                         true,
+	"logging":                      true,
+	"website":                      true,
+	"referer":                      true,
+	"lifecycle":                    true,
+	"delete":                       true,
+	"uploadId":                     true,
+	"partNumber":                   true,
+	"security-token":               true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+	"response-content-language":    true,
+	"response-content-type":        true,
+	"response-expires":             true,
+	"x-oss-process":                true,
+	"append":                       true,
+	"position":                     true,
+	"encryption":                   true,
+	"versioning":                   true,
+	"versions":                     true,
+	"versionId":                    true,
+	"tagging":                      true,
+	"symlink":                      true,
+	"restore":                      true,
+}
@@ -2,9 +2,12 @@ package oss
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
+	"hash/crc64"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -15,13 +18,26 @@ import (
 )
 
 type OssApi struct {
-	region, accessKeyId, accessKeySecret, bucket string
-	secure                                       bool
+	region, bucket    string
+	secure            bool
+	endpoint          string
+	credentials       *credentialsCache
+	signatureVersion  SignatureVersion
+	additionalHeaders []string
+	logger            Logger
+	preSignHooks      []PreSignHook
+	middlewares       []Middleware
+	skipCRC           bool
+	uploadLimiter     *rateLimiter
+	downloadLimiter   *rateLimiter
+	transport         http.RoundTripper
 }
 
 type part struct {
 	PartNumber int
 	ETag       string
+	Size       int64  `xml:"-"`
+	CRC64      string `xml:"-"` // the x-oss-hash-crc64ecma of the part, empty if unknown
 }
 type partSlice []part
 type UploadContext struct {
@@ -33,10 +49,9 @@ func (s partSlice) Len() int           { return len(s) }
 func (s partSlice) Less(i, j int) bool { return s[i].PartNumber < s[j].PartNumber }
 func (s partSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
-func (context *UploadContext) addPart(partNumber int, etag string) {
-	newpart := part{partNumber, etag}
+func (context *UploadContext) addPart(newpart part) {
 	for i, part := range context.Parts {
-		if partNumber == part.PartNumber {
+		if newpart.PartNumber == part.PartNumber {
 			context.Parts[i] = newpart
 			return
 		}
@@ -45,16 +60,46 @@ func (context *UploadContext) addPart(partNumber int, etag string) {
 }
 
 type request struct {
-	method  string
-	object  string
-	params  url.Values
-	headers http.Header
-	baseurl string
-	payload []byte
+	operation string // the oss api name, e.g. "PutObject"
+	method    string
+	object    string
+	params    url.Values
+	headers   http.Header
+	baseurl   string
+	payload   []byte
+	progress  ProgressListener
+	limiter   *rateLimiter
+	ctx       context.Context
+
+	copySourceVersionId string
 }
 
 func New(region, accessKeyId, accessKeySecret, bucket string, secure bool) *OssApi {
-	return &OssApi{region, accessKeyId, accessKeySecret, bucket, secure}
+	return NewWithSecurityToken(region, accessKeyId, accessKeySecret, "", bucket, secure)
+}
+
+// NewWithSecurityToken uses the STS temporary credentials,
+// which can't be refreshed after they expire
+func NewWithSecurityToken(region, accessKeyId, accessKeySecret, securityToken, bucket string, secure bool) *OssApi {
+	return NewWithProvider(region, bucket, secure, NewStaticCredentialsProvider(accessKeyId, accessKeySecret, securityToken))
+}
+
+// NewWithRefresher calls refresh for the first request
+// and again shortly before the returned credentials expire
+func NewWithRefresher(region, bucket string, secure bool, refresh CredentialsRefresher) *OssApi {
+	return NewWithProvider(region, bucket, secure, refresh)
+}
+
+// NewWithProvider retrieves the credentials from provider,
+// use DefaultCredentialsProvider() for the standard lookup chain
+func NewWithProvider(region, bucket string, secure bool, provider CredentialsProvider) *OssApi {
+	return &OssApi{
+		region:      region,
+		bucket:      bucket,
+		secure:      secure,
+		credentials: &credentialsCache{provider: provider},
+		logger:      nopLogger{},
+	}
 }
 
 func (api *OssApi) ListFiles(object, delimiter, marker string, max int) ([]string, []string, string, error) {
@@ -74,8 +119,9 @@ func (api *OssApi) ListFiles(object, delimiter, marker string, max int) ([]strin
 		params["max-keys"] = []string{strconv.Itoa(max)}
 	}
 	req := &request{
-		method: "GET",
-		params: params,
+		operation: "ListObjects",
+		method:    "GET",
+		params:    params,
 	}
 
 	var resp struct {
@@ -108,18 +154,61 @@ func (api *OssApi) ListFiles(object, delimiter, marker string, max int) ([]strin
 	return contents, resp.CommonPrefixes.Prefix, nextMarker, nil
 }
 
-func (api *OssApi) PutObject(object string, contents []byte, contentType string) error {
+func (api *OssApi) PutObject(object string, contents []byte, contentType string, options ...Option) error {
+	req := api.putObjectRequest(object, contents, contentType, options)
+	hresp, err := api.rawQuery(req)
+	if err != nil {
+		return err
+	}
+	hresp.Body.Close()
+	return api.checkCRC(req, hresp, CRC64(contents))
+}
+
+// AppendObject appends contents to object at position, 0 creates the appendable object.
+// crc is the CRC64 of the object before the append, the next position and the CRC64
+// of the object after the append are returned for the next call
+func (api *OssApi) AppendObject(object string, contents []byte, position int64, crc uint64, options ...Option) (int64, uint64, error) {
 	object = noramilizeObject(object)
 	req := &request{
-		method: "PUT",
-		object: object,
+		operation: "AppendObject",
+		method:    "POST",
+		object:    object,
+		params: map[string][]string{
+			"append":   {""},
+			"position": {strconv.FormatInt(position, 10)},
+		},
+		payload: contents,
+	}
+	req.apply(options)
+	hresp, err := api.rawQuery(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	hresp.Body.Close()
+	crc = CRC64Combine(crc, CRC64(contents), uint64(len(contents)))
+	if err := api.checkCRC(req, hresp, crc); err != nil {
+		return 0, 0, err
+	}
+	next, err := strconv.ParseInt(hresp.Header.Get("x-oss-next-append-position"), 10, 64)
+	if err != nil {
+		next = position + int64(len(contents))
+	}
+	return next, crc, nil
+}
+
+func (api *OssApi) putObjectRequest(object string, contents []byte, contentType string, options []Option) *request {
+	object = noramilizeObject(object)
+	req := &request{
+		operation: "PutObject",
+		method:    "PUT",
+		object:    object,
 		headers: map[string][]string{
 			"Content-Type": {contentType},
 		},
 		payload: contents,
 	}
-	return api.query(req, nil)
-	return nil
+	req.apply(options)
+	return req
 }
 
 type Header struct {
@@ -140,12 +229,14 @@ func (header *Header) GetLastModified() (time.Time, error) {
 	return http.ParseTime(header.Get("Last-Modified"))
 }
 
-func (api *OssApi) GetObjectMetadata(object string) (*Header, error) {
+func (api *OssApi) GetObjectMetadata(object string, options ...Option) (*Header, error) {
 	object = noramilizeObject(object)
 	req := &request{
-		method: "HEAD",
-		object: object,
+		operation: "HeadObject",
+		method:    "HEAD",
+		object:    object,
 	}
+	req.apply(options)
 	hresp, err := api.rawQuery(req)
 	if err != nil {
 		return nil, err
@@ -157,19 +248,40 @@ func (api *OssApi) GetObjectMetadata(object string) (*Header, error) {
 type ReaderWithBytes struct {
 	io.ReadCloser
 	bytes []byte
+	err   error
 }
 
-func (r *ReaderWithBytes) Bytes() []byte {
-	if r.bytes != nil {
-		return r.bytes
+// ReadAll reads the rest of the body once, the error includes the IntegrityError
+// of a whole object whose crc doesn't match
+func (r *ReaderWithBytes) ReadAll() ([]byte, error) {
+	if r.bytes != nil || r.err != nil {
+		return r.bytes, r.err
 	}
 	var buffer bytes.Buffer
-	buffer.ReadFrom(r)
+	_, r.err = buffer.ReadFrom(r)
 	r.bytes = buffer.Bytes()
-	return r.bytes
+	return r.bytes, r.err
 }
 
-func (api *OssApi) GetObjectRange(object string, start, end int64) (*ReaderWithBytes, int, error) {
+// Bytes returns nil if the read failed, use ReadAll to get the error
+func (r *ReaderWithBytes) Bytes() []byte {
+	data, err := r.ReadAll()
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (api *OssApi) GetObjectRange(object string, start, end int64, options ...Option) (*ReaderWithBytes, int, error) {
+	hresp, err := api.getObject(object, start, end, options)
+	if err != nil {
+		return nil, -1, err
+	}
+	return &ReaderWithBytes{ReadCloser: hresp.Body}, hresp.StatusCode, nil
+}
+
+// getObject returns the response whose body is the caller to close
+func (api *OssApi) getObject(object string, start, end int64, options []Option) (*http.Response, error) {
 	object = noramilizeObject(object)
 	var headers = make(map[string][]string)
 	if start >= 0 || end >= 0 {
@@ -183,32 +295,39 @@ func (api *OssApi) GetObjectRange(object string, start, end int64) (*ReaderWithB
 		}
 	}
 	req := &request{
-		method:  "GET",
-		object:  object,
-		headers: headers,
+		operation: "GetObject",
+		method:    "GET",
+		object:    object,
+		headers:   headers,
 	}
+	req.apply(options)
 	hresp, err := api.rawQuery(req)
 	if err != nil {
-		return nil, -1, err
+		return nil, err
 	}
-
-	return &ReaderWithBytes{hresp.Body, nil}, hresp.StatusCode, nil
+	// only the whole object can be checked against its crc
+	if hresp.StatusCode == http.StatusOK && !api.skipCRC {
+		hresp.Body = &crcReadCloser{hresp.Body, crc64.New(crcTable), api, req, hresp}
+	}
+	return hresp, nil
 }
 
-func (api *OssApi) GetObject(object string) ([]byte, error) {
+func (api *OssApi) GetObject(object string, options ...Option) ([]byte, error) {
 	object = noramilizeObject(object)
-	r, _, err := api.GetObjectRange(object, -1, -1)
+	r, _, err := api.GetObjectRange(object, -1, -1, options...)
 	if err != nil {
 		return nil, err
 	}
-	return r.Bytes(), nil
+	defer r.Close()
+	return ioutil.ReadAll(r)
 }
 
-func (api *OssApi) InitMultipartUpload(object, contentType string) (*UploadContext, error) {
+func (api *OssApi) InitMultipartUpload(object, contentType string, options ...Option) (*UploadContext, error) {
 	object = noramilizeObject(object)
 	req := &request{
-		method: "POST",
-		object: object,
+		operation: "InitiateMultipartUpload",
+		method:    "POST",
+		object:    object,
 		headers: map[string][]string{
 			"Content-Type": {contentType},
 		},
@@ -216,6 +335,7 @@ func (api *OssApi) InitMultipartUpload(object, contentType string) (*UploadConte
 			"uploads": {""},
 		},
 	}
+	req.apply(options)
 
 	var resp struct {
 		UploadId string `xml:"UploadId"`
@@ -258,8 +378,9 @@ func (api *OssApi) ListMultipartUploads(object string, marker *ListMultipartUplo
 		params["max-uploads"] = []string{strconv.Itoa(max)}
 	}
 	req := &request{
-		method: "GET",
-		params: params,
+		operation: "ListMultipartUploads",
+		method:    "GET",
+		params:    params,
 	}
 
 	var resp struct {
@@ -296,8 +417,9 @@ func (api *OssApi) ListMultipartUploads(object string, marker *ListMultipartUplo
 
 func (api *OssApi) FetchMultipartUploadParts(context *UploadContext) error {
 	req := &request{
-		method: "GET",
-		object: context.Key,
+		operation: "ListParts",
+		method:    "GET",
+		object:    context.Key,
 		params: map[string][]string{
 			"uploadId": {context.UploadId},
 		},
@@ -305,8 +427,10 @@ func (api *OssApi) FetchMultipartUploadParts(context *UploadContext) error {
 
 	var resp struct {
 		Part []struct {
-			PartNumber int
-			ETag       string
+			PartNumber    int
+			ETag          string
+			Size          int64
+			HashCrc64ecma string
 		}
 	}
 
@@ -315,16 +439,17 @@ func (api *OssApi) FetchMultipartUploadParts(context *UploadContext) error {
 		return err
 	}
 
-	for _, part := range resp.Part {
-		context.addPart(part.PartNumber, part.ETag)
+	for _, p := range resp.Part {
+		context.addPart(part{p.PartNumber, p.ETag, p.Size, p.HashCrc64ecma})
 	}
 	return nil
 }
 
-func (api *OssApi) UploadMultipart(context *UploadContext, contents []byte, partNumber int) error {
+func (api *OssApi) UploadMultipart(context *UploadContext, contents []byte, partNumber int, options ...Option) error {
 	req := &request{
-		method: "PUT",
-		object: context.Key,
+		operation: "UploadPart",
+		method:    "PUT",
+		object:    context.Key,
 
 		params: map[string][]string{
 			"partNumber": {strconv.Itoa(partNumber)},
@@ -332,17 +457,39 @@ func (api *OssApi) UploadMultipart(context *UploadContext, contents []byte, part
 		},
 		payload: contents,
 	}
+	req.apply(options)
 	hresp, err := api.rawQuery(req)
 	if err != nil {
 		return err
 	}
+	hresp.Body.Close()
+	crc := CRC64(contents)
+	if err := api.checkCRC(req, hresp, crc); err != nil {
+		return err
+	}
 	etag := hresp.Header.Get("ETag")
-	context.addPart(partNumber, etag)
+	context.addPart(part{partNumber, etag, int64(len(contents)), strconv.FormatUint(crc, 10)})
 	return nil
 }
 
-func (api *OssApi) CompleteMultipart(context *UploadContext) error {
+// CompleteMultipart checks the crc of the object if the crc of all the parts are known
+func (api *OssApi) CompleteMultipart(context *UploadContext, options ...Option) error {
+	req, err := completeMultipartRequest(context, options)
+	if err != nil {
+		return err
+	}
+	hresp, err := api.rawQuery(req)
+	if err != nil {
+		return err
+	}
+	hresp.Body.Close()
+	if crc, ok := partsCRC(context.Parts); ok {
+		return api.checkCRC(req, hresp, crc)
+	}
+	return nil
+}
 
+func completeMultipartRequest(context *UploadContext, options []Option) (*request, error) {
 	var completeUpload struct {
 		XMLName xml.Name  `xml:"CompleteMultipartUpload"`
 		Parts   partSlice `xml:"Part"`
@@ -351,21 +498,32 @@ func (api *OssApi) CompleteMultipart(context *UploadContext) error {
 	sort.Sort(completeUpload.Parts)
 	data, err := xml.Marshal(&completeUpload)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req := &request{
-		method: "POST",
-		object: context.Key,
+		operation: "CompleteMultipartUpload",
+		method:    "POST",
+		object:    context.Key,
 
 		params: map[string][]string{
 			"uploadId": {context.UploadId},
 		},
 		payload: data,
 	}
-	return api.query(req, nil)
+	req.apply(options)
+	return req, nil
+}
+
+func (api *OssApi) UploadCopyMultipart(context *UploadContext, sourceBucket, sourceObject string, start, end int64, partNumber int, options ...Option) (int64, error) {
+	copied, total, err := api.uploadCopyPart(context, sourceBucket, sourceObject, start, end, partNumber, options)
+	if err != nil {
+		return 0, err
+	}
+	return total - copied, nil
 }
 
-func (api *OssApi) UploadCopyMultipart(context *UploadContext, sourceBucket, sourceObject string, start, end int64, partNumber int) (int64, error) {
+// uploadCopyPart returns the offset after the copied range and the size of the source object
+func (api *OssApi) uploadCopyPart(context *UploadContext, sourceBucket, sourceObject string, start, end int64, partNumber int, options []Option) (int64, int64, error) {
 	sourceObject = noramilizeObject(sourceObject)
 	if sourceBucket == "" {
 		sourceBucket = api.bucket
@@ -384,18 +542,23 @@ func (api *OssApi) UploadCopyMultipart(context *UploadContext, sourceBucket, sou
 		}
 	}
 	req := &request{
-		method:  "PUT",
-		object:  context.Key,
-		headers: headers,
+		operation: "UploadPartCopy",
+		method:    "PUT",
+		object:    context.Key,
+		headers:   headers,
 		params: map[string][]string{
 			"partNumber": {strconv.Itoa(partNumber)},
 			"uploadId":   {context.UploadId},
 		},
 	}
+	req.apply(options)
+	if req.copySourceVersionId != "" {
+		req.headers["x-oss-copy-source"][0] += "?versionId=" + req.copySourceVersionId
+	}
 
 	hresp, err := api.rawQuery(req)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	var resp struct {
 		ETag string
@@ -403,39 +566,65 @@ func (api *OssApi) UploadCopyMultipart(context *UploadContext, sourceBucket, sou
 	err = xml.NewDecoder(hresp.Body).Decode(&resp)
 	hresp.Body.Close()
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	contentRange := hresp.Header.Get("Content-Range")
 	re := regexp.MustCompile("bytes (\\d+)-(\\d+)/(\\d+)")
 	matchResult := re.FindAllStringSubmatch(contentRange, -1)
-	remaining := int64(0)
-	if len(matchResult) != 0 {
-		total, _ := strconv.ParseInt(matchResult[0][3], 10, 64)
-		read, _ := strconv.ParseInt(matchResult[0][2], 10, 64)
-		remaining = total - read - 1
-	}
-	context.addPart(partNumber, resp.ETag)
-	return remaining, nil
+	copied, total := int64(0), int64(0)
+	if len(matchResult) == 0 {
+		// the size of the part is unknown, so is the crc of the completed object
+		context.addPart(part{partNumber, resp.ETag, 0, ""})
+		return copied, total, nil
+	}
+	total, _ = strconv.ParseInt(matchResult[0][3], 10, 64)
+	read, _ := strconv.ParseInt(matchResult[0][2], 10, 64)
+	copied = read + 1
+	context.addPart(part{partNumber, resp.ETag, copied - start, hresp.Header.Get("x-oss-hash-crc64ecma")})
+	return copied, total, nil
 
 }
 
-func (api *OssApi) Copy(sourceBucket, sourceObject, target, contentType string, chunkSize int64) error {
+// Copy copies the source object with a multipart upload of chunkSize parts,
+// the options apply to the InitMultipartUpload of target, the customer keys
+// to every part too and ForbidOverwrite to the CompleteMultipart. With the Progress option the listener receives
+// the part events and the TotalBytes once the first part is copied.
+func (api *OssApi) Copy(sourceBucket, sourceObject, target, contentType string, chunkSize int64, options ...Option) error {
 	sourceObject = noramilizeObject(sourceObject)
 	target = noramilizeObject(target)
 	if chunkSize < 100*1024 {
 		chunkSize = 100 * 1024
 	}
+	listener := progressOf(options)
+	progress := func(eventType ProgressEventType, partNumber int, consumed, total, rwBytes int64, err error) {
+		if listener != nil {
+			listener(&ProgressEvent{eventType, partNumber, consumed, total, rwBytes, err})
+		}
+	}
 
-	context, err := api.InitMultipartUpload(target, contentType)
+	initOptions, partOptions, completeOptions := copyOptions(options)
+	context, err := api.InitMultipartUpload(target, contentType, initOptions...)
+	if err != nil {
+		return err
+	}
+	progress(TransferStarted, 0, 0, -1, 0, nil)
 	start := int64(0)
 	end := int64(start + chunkSize - 1)
 	partNumber := 1
+	consumed, total := int64(0), int64(-1)
 	for {
-		remaining, err := api.UploadCopyMultipart(context, sourceBucket, sourceObject, start, end, partNumber)
+		progress(PartStarted, partNumber, consumed, total, 0, nil)
+		copied, size, err := api.uploadCopyPart(context, sourceBucket, sourceObject, start, end, partNumber, partOptions)
 		if err != nil {
+			progress(PartFailed, partNumber, consumed, total, 0, err)
+			progress(TransferFailed, 0, consumed, total, 0, err)
+			api.AbortMultipart(context)
 			return err
-			defer api.AbortMultipart(context)
 		}
+		rwBytes := copied - consumed
+		consumed, total = copied, size
+		progress(PartCompleted, partNumber, consumed, total, rwBytes, nil)
+		remaining := total - copied
 		if remaining == 0 {
 			break
 		}
@@ -447,18 +636,21 @@ func (api *OssApi) Copy(sourceBucket, sourceObject, target, contentType string,
 		}
 		partNumber++
 	}
-	err = api.CompleteMultipart(context)
+	err = api.CompleteMultipart(context, completeOptions...)
 	if err != nil {
+		progress(TransferFailed, 0, consumed, total, 0, err)
 		defer api.AbortMultipart(context)
 		return err
 	}
+	progress(TransferCompleted, 0, consumed, total, 0, nil)
 	return nil
 }
 
 func (api *OssApi) AbortMultipart(context *UploadContext) error {
 	req := &request{
-		method: "DELETE",
-		object: context.Key,
+		operation: "AbortMultipartUpload",
+		method:    "DELETE",
+		object:    context.Key,
 		params: map[string][]string{
 			"uploadId": {context.UploadId},
 		},
@@ -482,7 +674,8 @@ func (api *OssApi) Delete(objects ...string) error {
 	}
 	data, _ := xml.Marshal(&multipleDelete)
 	req := &request{
-		method: "POST",
+		operation: "DeleteMultipleObjects",
+		method:    "POST",
 		params: map[string][]string{
 			"delete": {""},
 		},
@@ -491,21 +684,60 @@ func (api *OssApi) Delete(objects ...string) error {
 	return api.query(req, nil)
 }
 
+// GeneratePresignedUrl returns a GET url valid for expiration seconds,
+// or an empty string if the credentials can't be refreshed, the error is logged
+//
+// Deprecated: use PresignUrl or PresignUrlFor which return the error
 func (api *OssApi) GeneratePresignedUrl(object string, expiration int64) string {
+	u, err := api.PresignUrlFor("GET", object, time.Duration(expiration)*time.Second)
+	if err != nil {
+		api.logger.Debug("oss presign failed", "object", object, "error", err)
+		return ""
+	}
+	return u
+}
+
+// PresignUrlFor returns an url valid for d
+func (api *OssApi) PresignUrlFor(method, object string, d time.Duration, options ...Option) (string, error) {
+	return api.PresignUrl(method, object, time.Now().Add(d), options...)
+}
+
+// PresignUrl returns an url of method on object valid until expires.
+// The headers set by options, e.g. ContentType, are signed and must be sent
+// as is by the client, the query parameters are added to the url.
+func (api *OssApi) PresignUrl(method, object string, expires time.Time, options ...Option) (string, error) {
 	object = noramilizeObject(object)
+	creds, err := api.credentials.get()
+	if err != nil {
+		return "", err
+	}
 	req := &request{
-		method: "GET",
-		params: map[string][]string{
-			"Expires": {strconv.FormatInt(time.Now().Unix()+expiration*int64(time.Second), 10)},
-		},
-		object: object,
+		method:  method,
+		object:  object,
+		params:  make(map[string][]string),
+		headers: make(map[string][]string),
+		baseurl: api.baseUrl(),
 	}
+	req.apply(options)
 
-	signature := api.sign(req)
-	req.params["OSSAccessKeyId"] = []string{api.accessKeyId}
-	req.params["Signature"] = []string{signature}
+	if api.signatureVersion == SignatureV4 {
+		now := time.Now()
+		api.presignV4(req, creds, now, expires.Unix()-now.Unix())
+	} else {
+		req.params["Expires"] = []string{strconv.FormatInt(expires.Unix(), 10)}
+		if creds.SecurityToken != "" {
+			req.params["security-token"] = []string{creds.SecurityToken}
+		}
+		signature := api.sign(req, creds)
+		req.params["OSSAccessKeyId"] = []string{creds.AccessKeyId}
+		req.params["Signature"] = []string{signature}
+	}
 
-	return api.baseUrl() + "/" + object + "?" + url.Values(req.params).Encode()
+	u, err := req.url()
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
 }
 
 func noramilizeObject(object string) string {
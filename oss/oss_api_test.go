@@ -3,7 +3,7 @@ package oss
 import (
 	"bytes"
 	"fmt"
-	log "github.com/Sirupsen/logrus"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
@@ -11,10 +11,13 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/topikachu/oss-mini-go-sdk/oss/ossfake"
 )
 
 type Config struct {
 	AccessKeyId, AccessKeySecret, Region, Bucket, LogLevel string
+	Endpoint                                               string
 }
 
 var config Config
@@ -39,11 +42,9 @@ func getNewFileName() string {
 }
 
 func setLogLevelFromConfig() {
-	logLevel, err := log.ParseLevel(strings.ToLower(config.LogLevel))
-	if err != nil {
-		log.SetLevel(log.InfoLevel)
-	} else {
-		log.SetLevel(logLevel)
+	if strings.ToLower(config.LogLevel) == "debug" {
+		handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+		api.SetLogger(NewSlogLogger(slog.New(handler)))
 	}
 }
 
@@ -58,6 +59,7 @@ var multipartFile5 = randomFolder + "multiobject5"
 var multipartFile6 = randomFolder + "multiobject6"
 var objectFile2 = randomFolder + "objectFile2"
 var objectFile3 = randomFolder + "objectFile3"
+var objectFile4 = randomFolder + "objectFile4"
 
 var folderNameForList = randomFolder + "listfolder/"
 var fileNameForList = []string{
@@ -81,9 +83,11 @@ func TestMain(m *testing.M) {
 	logLevel := os.Getenv("OSS_LOG_LEVEL")
 	secure, _ := strconv.ParseBool(os.Getenv("OSS_SECURE"))
 
+	// without a real bucket the tests run against an in-memory one
+	var server *ossfake.Server
 	if accessKey == "" || secretKey == "" || bucket == "" || region == "" {
-		fmt.Printf("the env \naccessKey:%s\nsecretKey:%s\nbucket:%s\nregion:%s", accessKey, secretKey, bucket, region)
-		os.Exit(0)
+		accessKey, secretKey, bucket, region = "fake-key", "fake-secret", "fake-bucket", "oss-cn-hangzhou"
+		server = ossfake.NewServer(bucket, accessKey, secretKey)
 	}
 
 	config.AccessKeyId = accessKey
@@ -94,15 +98,17 @@ func TestMain(m *testing.M) {
 
 	rand.Seed(time.Now().UTC().UnixNano())
 
-	setLogLevelFromConfig()
-
 	contents = bytes.NewBufferString(randSeq(1024 * 1024)).Bytes()
 	api = New(config.Region, config.AccessKeyId, config.AccessKeySecret, config.Bucket, secure)
 	if api == nil {
 		panic("Unable new oss")
 	}
+	if server != nil {
+		config.Endpoint = server.URL
+		api.SetEndpoint(config.Endpoint)
+	}
+	setLogLevelFromConfig()
 	err := api.PutObject(objectFile1, contents, "text/plain")
-	log.Infof("put object %s", objectFile1)
 	if err != nil {
 		panic("Unable put object:" + err.Error())
 	}
@@ -111,6 +117,9 @@ func TestMain(m *testing.M) {
 	for _, context := range contextList {
 		api.AbortMultipart(context)
 	}
+	if server != nil {
+		server.Close()
+	}
 	os.Exit(ret)
 }
 
@@ -118,19 +127,19 @@ func TestGetObjectMetadata(t *testing.T) {
 	header, err := api.GetObjectMetadata(objectFile1)
 
 	if header == nil || err != nil {
-		t.Errorf("Unable get object metadata", err)
+		t.Errorf("Unable get object metadata %v", err)
 	}
 	_, err = header.GetContentLength()
 	if err != nil {
-		t.Errorf("No content length", err)
+		t.Errorf("No content length %v", err)
 	}
 	_, err = header.GetDate()
 	if err != nil {
-		t.Errorf("No Date", err)
+		t.Errorf("No Date %v", err)
 	}
 	_, err = header.GetLastModified()
 	if err != nil {
-		t.Errorf("No LastModified", err)
+		t.Errorf("No LastModified %v", err)
 	}
 
 }
@@ -147,7 +156,7 @@ func TestGetFolderMetadata(t *testing.T) {
 func TestGetObject(t *testing.T) {
 	received, err := api.GetObject(objectFile1)
 	if err != nil {
-		t.Errorf("Unable get object: %s", objectFile1, err)
+		t.Errorf("Unable get object: %s %v", objectFile1, err)
 	}
 	if len(received) == 0 {
 		t.Errorf("no content received")
@@ -165,7 +174,7 @@ func TestGetObjectRangeStartEnd(t *testing.T) {
 	r, statusCode, err := api.GetObjectRange(objectFile1, 1, 20)
 	received := r.Bytes()
 	if err != nil {
-		t.Errorf("Unable get object: %s", objectFile1, err)
+		t.Errorf("Unable get object: %s %v", objectFile1, err)
 	}
 	if statusCode != 206 {
 		t.Errorf("wrong status code expected %d, actual %d", 206, statusCode)
@@ -188,7 +197,7 @@ func TestGetObjectRangeOnlyStart(t *testing.T) {
 	r, statusCode, err := api.GetObjectRange(objectFile1, 1, -1)
 	received := r.Bytes()
 	if err != nil {
-		t.Errorf("Unable get object: %s", objectFile1, err)
+		t.Errorf("Unable get object: %s %v", objectFile1, err)
 	}
 	if statusCode != 206 {
 		t.Errorf("wrong status code expected %d, actual %d", 206, statusCode)
@@ -211,7 +220,7 @@ func TestGetObjectRangeNoStartNoEnd(t *testing.T) {
 	r, statusCode, err := api.GetObjectRange(objectFile1, -1, -1)
 	received := r.Bytes()
 	if err != nil {
-		t.Errorf("Unable get object: %s", objectFile1, err)
+		t.Errorf("Unable get object: %s %v", objectFile1, err)
 	}
 	if statusCode != 200 {
 		t.Errorf("wrong status code expected %d, actual %d", 200, statusCode)
@@ -233,15 +242,15 @@ func TestMultiUpload(t *testing.T) {
 
 	context, err := api.InitMultipartUpload(multipartFile1, "text/plain")
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 	err = api.UploadMultipart(context, contents, 1)
 	if err != nil {
-		t.Errorf("cant upload multi part", err)
+		t.Errorf("cant upload multi part %v", err)
 	}
 	err = api.CompleteMultipart(context)
 	if err != nil {
-		t.Errorf("cant complete multi part", err)
+		t.Errorf("cant complete multi part %v", err)
 	}
 	received, err := api.GetObject(multipartFile1)
 	if bytes.Compare(contents, received) != 0 {
@@ -253,19 +262,19 @@ func TestListMultiUpload(t *testing.T) {
 
 	_, err := api.InitMultipartUpload(multipartFile2, "text/plain")
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 	_, err = api.InitMultipartUpload(multipartFile2, "text/plain")
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 	_, err = api.InitMultipartUpload(multipartFile2, "text/plain")
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 	contextList, _, err := api.ListMultipartUploads(multipartFile2, nil, -1)
 	if err != nil {
-		t.Errorf("cant list multi upload", err)
+		t.Errorf("cant list multi upload %v", err)
 	}
 	if len(contextList) != 3 {
 		t.Errorf("wrong upload context size expected %d, actual %d", 3, len(contextList))
@@ -279,19 +288,19 @@ func TestListMultiUpload(t *testing.T) {
 func TestFetchMultiUpload(t *testing.T) {
 	context, err := api.InitMultipartUpload(multipartFile3, "text/plain")
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 	err = api.UploadMultipart(context, contents, 1)
 	if err != nil {
-		t.Errorf("cant upload multi part", err)
+		t.Errorf("cant upload multi part %v", err)
 	}
 	err = api.UploadMultipart(context, contents, 2)
 	if err != nil {
-		t.Errorf("cant upload multi part", err)
+		t.Errorf("cant upload multi part %v", err)
 	}
 	err = api.FetchMultipartUploadParts(context)
 	if err != nil {
-		t.Errorf("cant fetch multi part", err)
+		t.Errorf("cant fetch multi part %v", err)
 	}
 	if len(context.Parts) != 2 {
 		t.Errorf("wrong upload parts size expected %d, acutal%d", 2, len(context.Parts))
@@ -302,20 +311,20 @@ func TestFetchMultiUpload(t *testing.T) {
 func TestUploadCopyMultipart(t *testing.T) {
 	context, err := api.InitMultipartUpload(multipartFile4, "text/plain")
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 
 	remaining, err := api.UploadCopyMultipart(context, "", objectFile1, -1, -1, 1)
 	if err != nil {
-		t.Errorf("cant uploadcopy multi", err)
+		t.Errorf("cant uploadcopy multi %v", err)
 	}
 	if remaining != 0 {
-		t.Errorf("Error, there's remaining &d bytes", remaining)
+		t.Errorf("Error, there's remaining %d bytes", remaining)
 	}
 
 	err = api.CompleteMultipart(context)
 	if err != nil {
-		t.Errorf("cant complete multi part", err)
+		t.Errorf("cant complete multi part %v", err)
 	}
 
 	received, err := api.GetObject(multipartFile4)
@@ -329,11 +338,11 @@ func TestUploadCopyMultipartStartEnd(t *testing.T) {
 
 	context, err := api.InitMultipartUpload(multipartFile5, "text/plain")
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 	remaining, err := api.UploadCopyMultipart(context, "", objectFile1, 10, 20, 1)
 	if err != nil {
-		t.Errorf("cant uploadcopy multi", err)
+		t.Errorf("cant uploadcopy multi %v", err)
 	}
 
 	if remaining == 0 {
@@ -342,7 +351,7 @@ func TestUploadCopyMultipartStartEnd(t *testing.T) {
 
 	err = api.CompleteMultipart(context)
 	if err != nil {
-		t.Errorf("cant complete multi part", err)
+		t.Errorf("cant complete multi part %v", err)
 	}
 
 	received, err := api.GetObject(multipartFile5)
@@ -356,18 +365,18 @@ func TestUploadCopyMultipartSingleStart(t *testing.T) {
 
 	context, err := api.InitMultipartUpload(multipartFile6, "text/plain")
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 	remaining, err := api.UploadCopyMultipart(context, "", objectFile1, 10, -1, 1)
 	if err != nil {
-		t.Errorf("cant uploadcopy multi", err)
+		t.Errorf("cant uploadcopy multi %v", err)
 	}
 	if remaining != 0 {
 		t.Errorf("Error, there's remaining %d bytes", remaining)
 	}
 	err = api.CompleteMultipart(context)
 	if err != nil {
-		t.Errorf("cant complete multi part", err)
+		t.Errorf("cant complete multi part %v", err)
 	}
 
 	received, err := api.GetObject(multipartFile6)
@@ -381,7 +390,7 @@ func TestCopy(t *testing.T) {
 
 	err := api.Copy("", objectFile1, objectFile2, "text/plain", 0)
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 
 	received, err := api.GetObject(objectFile2)
@@ -395,7 +404,7 @@ func TestCopyBigChunk(t *testing.T) {
 
 	err := api.Copy("", objectFile1, objectFile3, "text/plain", 5*1024*1024)
 	if err != nil {
-		t.Errorf("cant init multi upload", err)
+		t.Errorf("cant init multi upload %v", err)
 	}
 
 	received, err := api.GetObject(objectFile3)
@@ -408,7 +417,7 @@ func TestCopyBigChunk(t *testing.T) {
 func TestListRoot(t *testing.T) {
 	_, _, _, err := api.ListFiles("/", "", "", -1)
 	if err != nil {
-		t.Errorf("cant ListFiles", err)
+		t.Errorf("cant ListFiles %v", err)
 	}
 }
 
@@ -419,7 +428,7 @@ func TestListFiles(t *testing.T) {
 
 	fileNames, folderNames, _, err := api.ListFiles(folderNameForList, "/", "", -1)
 	if err != nil {
-		t.Errorf("cant ListFiles", err)
+		t.Errorf("cant ListFiles %v", err)
 	}
 
 	if len(fileNames) != 2 {
@@ -440,7 +449,7 @@ func TestListFiles(t *testing.T) {
 	fileNames, folderNames, _, err = api.ListFiles(folderNameForList, "", "", -1)
 
 	if err != nil {
-		t.Errorf("cant ListFiles", err)
+		t.Errorf("cant ListFiles %v", err)
 	}
 
 	if len(fileNames) != 9 {
@@ -456,7 +465,7 @@ func TestListFiles(t *testing.T) {
 func TestDelete(t *testing.T) {
 	err := api.Delete(fileNameForList...)
 	if err != nil {
-		t.Errorf("delete failed", err)
+		t.Errorf("delete failed %v", err)
 	}
 }
 
@@ -465,7 +474,7 @@ func TestGeneratePresignedUrl(t *testing.T) {
 	fmt.Printf("=======url %s", url)
 	resp, err := http.Get(url)
 	if err != nil {
-		t.Errorf("generatePresignedUrl failed", err)
+		t.Errorf("generatePresignedUrl failed %v", err)
 	}
 
 	var buf bytes.Buffer
@@ -475,3 +484,25 @@ func TestGeneratePresignedUrl(t *testing.T) {
 	}
 
 }
+
+func TestPresignUrlPut(t *testing.T) {
+	url, err := api.PresignUrlFor("PUT", objectFile4, 2*time.Minute, ContentType("text/plain"))
+	if err != nil {
+		t.Fatalf("presignUrl failed %v", err)
+	}
+	req, _ := http.NewRequest("PUT", url, bytes.NewReader(contents))
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("put presigned url failed %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("wrong status code expected %d, actual %d", 200, resp.StatusCode)
+	}
+
+	received, err := api.GetObject(objectFile4)
+	if bytes.Compare(contents, received) != 0 {
+		t.Errorf("the received content are not same as sent")
+	}
+}
@@ -0,0 +1,320 @@
+package oss
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// CORSRule is a single cross-origin resource sharing rule of a bucket.
+type CORSRule struct {
+	AllowedOrigin []string `xml:"AllowedOrigin"`
+	AllowedMethod []string `xml:"AllowedMethod"`
+	AllowedHeader []string `xml:"AllowedHeader,omitempty"`
+	ExposeHeader  []string `xml:"ExposeHeader,omitempty"`
+	MaxAgeSeconds int      `xml:"MaxAgeSeconds,omitempty"`
+}
+
+type corsConfiguration struct {
+	XMLName   xml.Name   `xml:"CORSConfiguration"`
+	CORSRules []CORSRule `xml:"CORSRule"`
+}
+
+// bucketConfigName turns the sub resource "cors" into "Cors" for the operation names
+func bucketConfigName(subResource string) string {
+	return strings.ToUpper(subResource[:1]) + subResource[1:]
+}
+
+// putBucketConfig uploads the xml document config to the bucket sub resource
+func (api *OssApi) putBucketConfig(subResource string, config interface{}) error {
+	data, err := xml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	req := &request{
+		operation: "PutBucket" + bucketConfigName(subResource),
+		method:    "PUT",
+		params: map[string][]string{
+			subResource: {""},
+		},
+		payload: data,
+	}
+	return api.query(req, nil)
+}
+
+func (api *OssApi) getBucketConfig(subResource string, config interface{}) error {
+	req := &request{
+		operation: "GetBucket" + bucketConfigName(subResource),
+		method:    "GET",
+		params: map[string][]string{
+			subResource: {""},
+		},
+	}
+	return api.query(req, config)
+}
+
+func (api *OssApi) deleteBucketConfig(subResource string) error {
+	req := &request{
+		operation: "DeleteBucket" + bucketConfigName(subResource),
+		method:    "DELETE",
+		params: map[string][]string{
+			subResource: {""},
+		},
+	}
+	return api.query(req, nil)
+}
+
+func (api *OssApi) PutBucketCors(rules []CORSRule) error {
+	return api.putBucketConfig("cors", &corsConfiguration{CORSRules: rules})
+}
+
+func (api *OssApi) GetBucketCors() ([]CORSRule, error) {
+	var config corsConfiguration
+	err := api.getBucketConfig("cors", &config)
+	if err != nil {
+		return nil, err
+	}
+	return config.CORSRules, nil
+}
+
+func (api *OssApi) DeleteBucketCors() error {
+	return api.deleteBucketConfig("cors")
+}
+
+type StorageClass string
+
+const (
+	StorageStandard    StorageClass = "Standard"
+	StorageIA          StorageClass = "IA"
+	StorageArchive     StorageClass = "Archive"
+	StorageColdArchive StorageClass = "ColdArchive"
+)
+
+type Tag struct {
+	Key   string
+	Value string
+}
+
+const (
+	LifecycleEnabled  = "Enabled"
+	LifecycleDisabled = "Disabled"
+)
+
+// LifecycleDateFormat is the layout of the CreatedBeforeDate fields,
+// oss only accepts midnight UTC dates
+const LifecycleDateFormat = "2006-01-02T15:04:05.000Z"
+
+// LifecycleRule applies to the objects matching both Prefix and all Tags.
+// Status is either LifecycleEnabled or LifecycleDisabled.
+type LifecycleRule struct {
+	ID                          string                                `xml:"ID,omitempty"`
+	Prefix                      string                                `xml:"Prefix"`
+	Status                      string                                `xml:"Status"`
+	Tags                        []Tag                                 `xml:"Tag,omitempty"`
+	Expiration                  *LifecycleExpiration                  `xml:"Expiration,omitempty"`
+	Transitions                 []LifecycleTransition                 `xml:"Transition,omitempty"`
+	AbortMultipartUpload        *LifecycleAbortMultipartUpload        `xml:"AbortMultipartUpload,omitempty"`
+	NoncurrentVersionExpiration *LifecycleNoncurrentVersionExpiration `xml:"NoncurrentVersionExpiration,omitempty"`
+}
+
+// set either Days or CreatedBeforeDate
+type LifecycleExpiration struct {
+	Days                      int    `xml:"Days,omitempty"`
+	CreatedBeforeDate         string `xml:"CreatedBeforeDate,omitempty"`
+	ExpiredObjectDeleteMarker bool   `xml:"ExpiredObjectDeleteMarker,omitempty"`
+}
+
+type LifecycleTransition struct {
+	Days              int          `xml:"Days,omitempty"`
+	CreatedBeforeDate string       `xml:"CreatedBeforeDate,omitempty"`
+	StorageClass      StorageClass `xml:"StorageClass"`
+}
+
+type LifecycleAbortMultipartUpload struct {
+	Days              int    `xml:"Days,omitempty"`
+	CreatedBeforeDate string `xml:"CreatedBeforeDate,omitempty"`
+}
+
+type LifecycleNoncurrentVersionExpiration struct {
+	NoncurrentDays int `xml:"NoncurrentDays"`
+}
+
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+func (api *OssApi) PutBucketLifecycle(rules []LifecycleRule) error {
+	return api.putBucketConfig("lifecycle", &lifecycleConfiguration{Rules: rules})
+}
+
+func (api *OssApi) GetBucketLifecycle() ([]LifecycleRule, error) {
+	var config lifecycleConfiguration
+	err := api.getBucketConfig("lifecycle", &config)
+	if err != nil {
+		return nil, err
+	}
+	return config.Rules, nil
+}
+
+func (api *OssApi) DeleteBucketLifecycle() error {
+	return api.deleteBucketConfig("lifecycle")
+}
+
+type loggingEnabled struct {
+	TargetBucket string
+	TargetPrefix string
+}
+
+type bucketLoggingStatus struct {
+	XMLName        xml.Name `xml:"BucketLoggingStatus"`
+	LoggingEnabled *loggingEnabled
+}
+
+// PutBucketLogging writes the access logs of the bucket to targetBucket
+// with the key prefix targetPrefix
+func (api *OssApi) PutBucketLogging(targetBucket, targetPrefix string) error {
+	return api.putBucketConfig("logging", &bucketLoggingStatus{
+		LoggingEnabled: &loggingEnabled{targetBucket, targetPrefix},
+	})
+}
+
+// GetBucketLogging returns empty strings if the access logging is disabled
+func (api *OssApi) GetBucketLogging() (string, string, error) {
+	var status bucketLoggingStatus
+	err := api.getBucketConfig("logging", &status)
+	if err != nil {
+		return "", "", err
+	}
+	if status.LoggingEnabled == nil {
+		return "", "", nil
+	}
+	return status.LoggingEnabled.TargetBucket, status.LoggingEnabled.TargetPrefix, nil
+}
+
+func (api *OssApi) DeleteBucketLogging() error {
+	return api.deleteBucketConfig("logging")
+}
+
+// WebsiteConfiguration is the static website hosting of a bucket
+type WebsiteConfiguration struct {
+	XMLName       xml.Name       `xml:"WebsiteConfiguration"`
+	IndexDocument *IndexDocument `xml:"IndexDocument,omitempty"`
+	ErrorDocument *ErrorDocument `xml:"ErrorDocument,omitempty"`
+	RoutingRules  []RoutingRule  `xml:"RoutingRules>RoutingRule,omitempty"`
+}
+
+type IndexDocument struct {
+	Suffix string
+}
+
+type ErrorDocument struct {
+	Key string
+}
+
+// RoutingRule redirects the requests matching Condition, rules are
+// evaluated in the order of RuleNumber
+type RoutingRule struct {
+	RuleNumber int
+	Condition  RoutingCondition
+	Redirect   RoutingRedirect
+}
+
+type RoutingCondition struct {
+	KeyPrefixEquals             string                 `xml:"KeyPrefixEquals,omitempty"`
+	HttpErrorCodeReturnedEquals int                    `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+	IncludeHeaders              []RoutingIncludeHeader `xml:"IncludeHeader,omitempty"`
+}
+
+type RoutingIncludeHeader struct {
+	Key    string
+	Equals string
+}
+
+// RedirectType is one of "Mirror", "External", "Internal" or "AliCDN"
+type RoutingRedirect struct {
+	RedirectType         string `xml:"RedirectType"`
+	PassQueryString      bool   `xml:"PassQueryString,omitempty"`
+	MirrorURL            string `xml:"MirrorURL,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty"`
+	HostName             string `xml:"HostName,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+	HttpRedirectCode     int    `xml:"HttpRedirectCode,omitempty"`
+}
+
+func (api *OssApi) PutBucketWebsite(config *WebsiteConfiguration) error {
+	return api.putBucketConfig("website", config)
+}
+
+func (api *OssApi) GetBucketWebsite() (*WebsiteConfiguration, error) {
+	var config WebsiteConfiguration
+	err := api.getBucketConfig("website", &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (api *OssApi) DeleteBucketWebsite() error {
+	return api.deleteBucketConfig("website")
+}
+
+type refererConfiguration struct {
+	XMLName           xml.Name `xml:"RefererConfiguration"`
+	AllowEmptyReferer bool
+	Referers          []string `xml:"RefererList>Referer"`
+}
+
+// PutBucketReferer sets the hotlink protection whitelist of the bucket,
+// the referers may contain the * and ? wildcards
+func (api *OssApi) PutBucketReferer(allowEmptyReferer bool, referers []string) error {
+	return api.putBucketConfig("referer", &refererConfiguration{
+		AllowEmptyReferer: allowEmptyReferer,
+		Referers:          referers,
+	})
+}
+
+func (api *OssApi) GetBucketReferer() (bool, []string, error) {
+	var config refererConfiguration
+	err := api.getBucketConfig("referer", &config)
+	if err != nil {
+		return false, nil, err
+	}
+	return config.AllowEmptyReferer, config.Referers, nil
+}
+
+// oss has no delete operation for referer,
+// an empty whitelist allowing empty referer turns the protection off
+func (api *OssApi) DeleteBucketReferer() error {
+	return api.PutBucketReferer(true, nil)
+}
+
+// ServerSideEncryptionRule is the default encryption of the new objects of a bucket,
+// KMSMasterKeyID and KMSDataEncryption only apply to the SSEKMS algorithm
+type ServerSideEncryptionRule struct {
+	SSEAlgorithm      string `xml:"SSEAlgorithm"`
+	KMSMasterKeyID    string `xml:"KMSMasterKeyID,omitempty"`
+	KMSDataEncryption string `xml:"KMSDataEncryption,omitempty"`
+}
+
+type serverSideEncryptionConfiguration struct {
+	XMLName xml.Name                 `xml:"ServerSideEncryptionRule"`
+	Rule    ServerSideEncryptionRule `xml:"ApplyServerSideEncryptionByDefault"`
+}
+
+func (api *OssApi) PutBucketEncryption(rule ServerSideEncryptionRule) error {
+	return api.putBucketConfig("encryption", &serverSideEncryptionConfiguration{Rule: rule})
+}
+
+func (api *OssApi) GetBucketEncryption() (*ServerSideEncryptionRule, error) {
+	var config serverSideEncryptionConfiguration
+	err := api.getBucketConfig("encryption", &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config.Rule, nil
+}
+
+func (api *OssApi) DeleteBucketEncryption() error {
+	return api.deleteBucketConfig("encryption")
+}
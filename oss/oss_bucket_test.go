@@ -0,0 +1,237 @@
+package oss
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestCorsConfigurationXML(t *testing.T) {
+	config := corsConfiguration{
+		CORSRules: []CORSRule{
+			{
+				AllowedOrigin: []string{"https://www.example.com", "https://*.example.org"},
+				AllowedMethod: []string{"PUT", "GET"},
+				AllowedHeader: []string{"*"},
+				ExposeHeader:  []string{"ETag", "x-oss-request-id"},
+				MaxAgeSeconds: 600,
+			},
+			{
+				AllowedOrigin: []string{"*"},
+				AllowedMethod: []string{"HEAD"},
+			},
+		},
+	}
+	data, err := xml.Marshal(&config)
+	if err != nil {
+		t.Fatalf("cant marshal cors configuration %v", err)
+	}
+	expected := "<CORSConfiguration>" +
+		"<CORSRule>" +
+		"<AllowedOrigin>https://www.example.com</AllowedOrigin><AllowedOrigin>https://*.example.org</AllowedOrigin>" +
+		"<AllowedMethod>PUT</AllowedMethod><AllowedMethod>GET</AllowedMethod>" +
+		"<AllowedHeader>*</AllowedHeader>" +
+		"<ExposeHeader>ETag</ExposeHeader><ExposeHeader>x-oss-request-id</ExposeHeader>" +
+		"<MaxAgeSeconds>600</MaxAgeSeconds>" +
+		"</CORSRule>" +
+		"<CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>HEAD</AllowedMethod></CORSRule>" +
+		"</CORSConfiguration>"
+	if string(data) != expected {
+		t.Errorf("wrong cors xml expected %s, actual %s", expected, data)
+	}
+
+	var received corsConfiguration
+	if err := xml.Unmarshal(data, &received); err != nil {
+		t.Fatalf("cant unmarshal cors configuration %v", err)
+	}
+	if !reflect.DeepEqual(config.CORSRules, received.CORSRules) {
+		t.Errorf("the received cors rules are not same as sent %#v", received.CORSRules)
+	}
+}
+
+func TestLifecycleConfigurationXML(t *testing.T) {
+	config := lifecycleConfiguration{
+		Rules: []LifecycleRule{
+			{
+				ID:     "logs",
+				Prefix: "logs/",
+				Status: LifecycleEnabled,
+				Tags:   []Tag{{"team", "infra"}},
+				Expiration: &LifecycleExpiration{
+					Days: 365,
+				},
+				Transitions: []LifecycleTransition{
+					{Days: 30, StorageClass: StorageIA},
+					{Days: 180, StorageClass: StorageColdArchive},
+				},
+				AbortMultipartUpload: &LifecycleAbortMultipartUpload{
+					Days: 7,
+				},
+				NoncurrentVersionExpiration: &LifecycleNoncurrentVersionExpiration{
+					NoncurrentDays: 30,
+				},
+			},
+			{
+				ID:     "tmp",
+				Prefix: "tmp/",
+				Status: LifecycleDisabled,
+				Expiration: &LifecycleExpiration{
+					CreatedBeforeDate: "2023-01-01T00:00:00.000Z",
+				},
+			},
+		},
+	}
+	data, err := xml.Marshal(&config)
+	if err != nil {
+		t.Fatalf("cant marshal lifecycle configuration %v", err)
+	}
+	expected := "<LifecycleConfiguration>" +
+		"<Rule><ID>logs</ID><Prefix>logs/</Prefix><Status>Enabled</Status>" +
+		"<Tag><Key>team</Key><Value>infra</Value></Tag>" +
+		"<Expiration><Days>365</Days></Expiration>" +
+		"<Transition><Days>30</Days><StorageClass>IA</StorageClass></Transition>" +
+		"<Transition><Days>180</Days><StorageClass>ColdArchive</StorageClass></Transition>" +
+		"<AbortMultipartUpload><Days>7</Days></AbortMultipartUpload>" +
+		"<NoncurrentVersionExpiration><NoncurrentDays>30</NoncurrentDays></NoncurrentVersionExpiration>" +
+		"</Rule>" +
+		"<Rule><ID>tmp</ID><Prefix>tmp/</Prefix><Status>Disabled</Status>" +
+		"<Expiration><CreatedBeforeDate>2023-01-01T00:00:00.000Z</CreatedBeforeDate></Expiration>" +
+		"</Rule>" +
+		"</LifecycleConfiguration>"
+	if string(data) != expected {
+		t.Errorf("wrong lifecycle xml expected %s, actual %s", expected, data)
+	}
+
+	var received lifecycleConfiguration
+	if err := xml.Unmarshal(data, &received); err != nil {
+		t.Fatalf("cant unmarshal lifecycle configuration %v", err)
+	}
+	if !reflect.DeepEqual(config.Rules, received.Rules) {
+		t.Errorf("the received lifecycle rules are not same as sent %#v", received.Rules)
+	}
+}
+
+func TestLoggingStatusXML(t *testing.T) {
+	var status bucketLoggingStatus
+	err := xml.Unmarshal([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<BucketLoggingStatus>
+  <LoggingEnabled>
+    <TargetBucket>logs-bucket</TargetBucket>
+    <TargetPrefix>access/</TargetPrefix>
+  </LoggingEnabled>
+</BucketLoggingStatus>`), &status)
+	if err != nil {
+		t.Fatalf("cant unmarshal logging status %v", err)
+	}
+	if status.LoggingEnabled == nil || status.LoggingEnabled.TargetBucket != "logs-bucket" || status.LoggingEnabled.TargetPrefix != "access/" {
+		t.Errorf("wrong logging status %#v", status.LoggingEnabled)
+	}
+
+	status = bucketLoggingStatus{}
+	err = xml.Unmarshal([]byte(`<BucketLoggingStatus></BucketLoggingStatus>`), &status)
+	if err != nil {
+		t.Fatalf("cant unmarshal logging status %v", err)
+	}
+	if status.LoggingEnabled != nil {
+		t.Errorf("logging should be disabled %#v", status.LoggingEnabled)
+	}
+}
+
+func TestWebsiteConfigurationXML(t *testing.T) {
+	config := WebsiteConfiguration{
+		IndexDocument: &IndexDocument{"index.html"},
+		ErrorDocument: &ErrorDocument{"error.html"},
+		RoutingRules: []RoutingRule{
+			{
+				RuleNumber: 1,
+				Condition: RoutingCondition{
+					KeyPrefixEquals:             "abc/",
+					HttpErrorCodeReturnedEquals: 404,
+					IncludeHeaders:              []RoutingIncludeHeader{{"host", "test.oss-cn-beijing-internal.aliyuncs.com"}},
+				},
+				Redirect: RoutingRedirect{
+					RedirectType:         "External",
+					Protocol:             "https",
+					HostName:             "example.com",
+					ReplaceKeyPrefixWith: "def/",
+					HttpRedirectCode:     302,
+				},
+			},
+		},
+	}
+	data, err := xml.Marshal(&config)
+	if err != nil {
+		t.Fatalf("cant marshal website configuration %v", err)
+	}
+	expected := "<WebsiteConfiguration>" +
+		"<IndexDocument><Suffix>index.html</Suffix></IndexDocument>" +
+		"<ErrorDocument><Key>error.html</Key></ErrorDocument>" +
+		"<RoutingRules><RoutingRule><RuleNumber>1</RuleNumber>" +
+		"<Condition><KeyPrefixEquals>abc/</KeyPrefixEquals><HttpErrorCodeReturnedEquals>404</HttpErrorCodeReturnedEquals>" +
+		"<IncludeHeader><Key>host</Key><Equals>test.oss-cn-beijing-internal.aliyuncs.com</Equals></IncludeHeader></Condition>" +
+		"<Redirect><RedirectType>External</RedirectType><Protocol>https</Protocol><HostName>example.com</HostName>" +
+		"<ReplaceKeyPrefixWith>def/</ReplaceKeyPrefixWith><HttpRedirectCode>302</HttpRedirectCode></Redirect>" +
+		"</RoutingRule></RoutingRules>" +
+		"</WebsiteConfiguration>"
+	if string(data) != expected {
+		t.Errorf("wrong website xml expected %s, actual %s", expected, data)
+	}
+
+	var received WebsiteConfiguration
+	if err := xml.Unmarshal(data, &received); err != nil {
+		t.Fatalf("cant unmarshal website configuration %v", err)
+	}
+	received.XMLName = xml.Name{}
+	if !reflect.DeepEqual(config, received) {
+		t.Errorf("the received website configuration is not same as sent %#v", received)
+	}
+}
+
+func TestRefererConfigurationXML(t *testing.T) {
+	config := refererConfiguration{
+		AllowEmptyReferer: false,
+		Referers:          []string{"http://www.aliyun.com", "https://*.example.com"},
+	}
+	data, err := xml.Marshal(&config)
+	if err != nil {
+		t.Fatalf("cant marshal referer configuration %v", err)
+	}
+	expected := "<RefererConfiguration><AllowEmptyReferer>false</AllowEmptyReferer>" +
+		"<RefererList><Referer>http://www.aliyun.com</Referer><Referer>https://*.example.com</Referer></RefererList>" +
+		"</RefererConfiguration>"
+	if string(data) != expected {
+		t.Errorf("wrong referer xml expected %s, actual %s", expected, data)
+	}
+
+	var received refererConfiguration
+	if err := xml.Unmarshal(data, &received); err != nil {
+		t.Fatalf("cant unmarshal referer configuration %v", err)
+	}
+	if received.AllowEmptyReferer != config.AllowEmptyReferer || !reflect.DeepEqual(config.Referers, received.Referers) {
+		t.Errorf("the received referer configuration is not same as sent %#v", received)
+	}
+}
+
+func TestServerSideEncryptionRuleXML(t *testing.T) {
+	config := serverSideEncryptionConfiguration{
+		Rule: ServerSideEncryptionRule{SSEAlgorithm: SSEKMS, KMSMasterKeyID: "key", KMSDataEncryption: SSESM4},
+	}
+	data, err := xml.Marshal(&config)
+	if err != nil {
+		t.Fatalf("cant marshal encryption rule %v", err)
+	}
+	expected := "<ServerSideEncryptionRule><ApplyServerSideEncryptionByDefault>" +
+		"<SSEAlgorithm>KMS</SSEAlgorithm><KMSMasterKeyID>key</KMSMasterKeyID><KMSDataEncryption>SM4</KMSDataEncryption>" +
+		"</ApplyServerSideEncryptionByDefault></ServerSideEncryptionRule>"
+	if string(data) != expected {
+		t.Errorf("wrong encryption xml expected %s, actual %s", expected, data)
+	}
+
+	var received serverSideEncryptionConfiguration
+	if err := xml.Unmarshal(data, &received); err != nil {
+		t.Fatalf("cant unmarshal encryption rule %v", err)
+	}
+	if received.Rule != config.Rule {
+		t.Errorf("the received encryption rule is not same as sent %#v", received.Rule)
+	}
+}
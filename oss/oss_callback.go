@@ -0,0 +1,226 @@
+package oss
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// upload callback
+// https://help.aliyun.com/zh/oss/developer-reference/callback
+
+// Callback asks oss to POST to URL after the upload completes.
+// Body may reference the system variables like ${object} and ${size}
+// and the custom variables like ${x:user}.
+type Callback struct {
+	URL      string `json:"callbackUrl"` // several urls are separated by ;
+	Host     string `json:"callbackHost,omitempty"`
+	Body     string `json:"callbackBody"`
+	BodyType string `json:"callbackBodyType,omitempty"` // application/x-www-form-urlencoded or application/json
+	SNI      bool   `json:"callbackSNI,omitempty"`
+}
+
+// Encode returns the value of the x-oss-callback header or the callback form field
+func (callback *Callback) Encode() string {
+	data, _ := json.Marshal(callback)
+	return b64.EncodeToString(data)
+}
+
+// EncodeCallbackVar returns the value of the x-oss-callback-var header,
+// the keys must start with "x:" and be lower cased
+func EncodeCallbackVar(vars map[string]string) string {
+	data, _ := json.Marshal(vars)
+	return b64.EncodeToString(data)
+}
+
+func WithCallback(callback *Callback, vars map[string]string) Option {
+	return func(req *request) {
+		req.setHeader("x-oss-callback", callback.Encode())
+		if len(vars) > 0 {
+			req.setHeader("x-oss-callback-var", EncodeCallbackVar(vars))
+		}
+	}
+}
+
+// PutObjectWithCallback returns the body the callback server responded with
+func (api *OssApi) PutObjectWithCallback(object string, contents []byte, contentType string, callback *Callback, vars map[string]string, options ...Option) ([]byte, error) {
+	options = append(options, WithCallback(callback, vars))
+	return api.callbackQuery(api.putObjectRequest(object, contents, contentType, options), CRC64(contents), true)
+}
+
+// CompleteMultipartWithCallback returns the body the callback server responded with
+func (api *OssApi) CompleteMultipartWithCallback(context *UploadContext, callback *Callback, vars map[string]string, options ...Option) ([]byte, error) {
+	options = append(options, WithCallback(callback, vars))
+	req, err := completeMultipartRequest(context, options)
+	if err != nil {
+		return nil, err
+	}
+	crc, known := partsCRC(context.Parts)
+	return api.callbackQuery(req, crc, known)
+}
+
+// oss responds 203 with a CallbackFailed error if the upload succeeded
+// but the callback server failed. The crc of the uploaded object is checked if known
+func (api *OssApi) callbackQuery(req *request, crc uint64, known bool) ([]byte, error) {
+	hresp, err := api.rawQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	defer hresp.Body.Close()
+	if hresp.StatusCode == http.StatusNonAuthoritativeInfo {
+		return nil, buildError(req, hresp)
+	}
+	if known {
+		if err := api.checkCRC(req, hresp, crc); err != nil {
+			return nil, err
+		}
+	}
+	return ioutil.ReadAll(hresp.Body)
+}
+
+// CallbackVerifier is an http.Handler middleware rejecting the callback requests
+// not signed by oss
+type CallbackVerifier struct {
+	Handler http.Handler
+	// FetchPublicKey downloads the pem encoded public key at the x-oss-pub-key-url,
+	// it defaults to an http GET
+	FetchPublicKey func(url string) ([]byte, error)
+
+	lock sync.Mutex
+	keys map[string]*rsa.PublicKey
+}
+
+func NewCallbackVerifier(handler http.Handler) *CallbackVerifier {
+	return &CallbackVerifier{Handler: handler}
+}
+
+func (v *CallbackVerifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err == nil {
+		err = v.Verify(r, body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	v.Handler.ServeHTTP(w, r)
+}
+
+// Verify checks the authorization header of the callback request r with the given body
+func (v *CallbackVerifier) Verify(r *http.Request, body []byte) error {
+	signature, err := b64.DecodeString(r.Header.Get("Authorization"))
+	if err != nil || len(signature) == 0 {
+		return errors.New("oss: bad callback authorization")
+	}
+	keyUrl, err := b64.DecodeString(r.Header.Get("x-oss-pub-key-url"))
+	if err != nil {
+		return errors.New("oss: bad callback x-oss-pub-key-url")
+	}
+	key, err := v.publicKey(string(keyUrl))
+	if err != nil {
+		return err
+	}
+
+	payload := r.URL.Path
+	if r.URL.RawQuery != "" {
+		payload += "?" + r.URL.RawQuery
+	}
+	payload += "\n" + string(body)
+	digest := md5.Sum([]byte(payload))
+	if err := rsa.VerifyPKCS1v15(key, crypto.MD5, digest[:], signature); err != nil {
+		return errors.New("oss: callback signature does not match")
+	}
+	return nil
+}
+
+// only the keys published by oss are trusted
+func (v *CallbackVerifier) publicKey(keyUrl string) (*rsa.PublicKey, error) {
+	u, err := url.Parse(keyUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host != "gosspublic.alicdn.com" {
+		return nil, fmt.Errorf("oss: untrusted callback public key url %q", keyUrl)
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if key, ok := v.keys[u.Path]; ok {
+		return key, nil
+	}
+	fetch := v.FetchPublicKey
+	if fetch == nil {
+		fetch = fetchCallbackPublicKey
+	}
+	data, err := fetch(keyUrl)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("oss: callback public key is not pem encoded")
+	}
+	var key *rsa.PublicKey
+	if parsed, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		key, _ = parsed.(*rsa.PublicKey)
+	} else {
+		key, _ = x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	if key == nil {
+		return nil, errors.New("oss: callback public key is not a rsa key")
+	}
+	if v.keys == nil {
+		v.keys = make(map[string]*rsa.PublicKey)
+	}
+	v.keys[u.Path] = key
+	return key, nil
+}
+
+func fetchCallbackPublicKey(keyUrl string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	hresp, err := client.Get(keyUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer hresp.Body.Close()
+	if hresp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oss: cant fetch callback public key %s: %s", keyUrl, hresp.Status)
+	}
+	return ioutil.ReadAll(hresp.Body)
+}
+
+// ParseCallbackBody decodes the form or json body of a callback request
+// into a flat map of the callback variables
+func ParseCallbackBody(r *http.Request, body []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			return nil, err
+		}
+		for k, v := range decoded {
+			values[k] = fmt.Sprint(v)
+		}
+		return values, nil
+	}
+	query, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	for k := range query {
+		values[k] = query.Get(k)
+	}
+	return values, nil
+}
@@ -0,0 +1,130 @@
+package oss
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWithCallback(t *testing.T) {
+	callback := &Callback{
+		URL:      "https://example.com/callback",
+		Body:     "bucket=${bucket}&object=${object}&user=${x:user}",
+		BodyType: "application/x-www-form-urlencoded",
+	}
+	req := &request{}
+	req.apply([]Option{WithCallback(callback, map[string]string{"x:user": "me"})})
+
+	data, err := b64.DecodeString(req.headers["x-oss-callback"][0])
+	if err != nil {
+		t.Fatalf("x-oss-callback is not base64 encoded %v", err)
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	if decoded["callbackUrl"] != callback.URL || decoded["callbackBody"] != callback.Body || decoded["callbackBodyType"] != callback.BodyType {
+		t.Errorf("wrong x-oss-callback %s", data)
+	}
+	if _, ok := decoded["callbackHost"]; ok {
+		t.Errorf("empty callbackHost should be omitted %s", data)
+	}
+
+	data, _ = b64.DecodeString(req.headers["x-oss-callback-var"][0])
+	if string(data) != `{"x:user":"me"}` {
+		t.Errorf("wrong x-oss-callback-var %s", data)
+	}
+}
+
+func TestCallbackCRC(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	crc := strconv.FormatUint(CRC64([]byte("hello")), 10)
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Oss-Hash-Crc64ecma": {crc}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"status":"ok"}`)),
+		}
+	}))
+	callback := &Callback{URL: "https://example.com/callback", Body: "object=${object}"}
+	if body, err := client.PutObjectWithCallback("a.txt", []byte("hello"), "text/plain", callback, nil); err != nil || string(body) != `{"status":"ok"}` {
+		t.Errorf("wrong callback response %s %v", body, err)
+	}
+	if _, err := client.PutObjectWithCallback("a.txt", []byte("hellp"), "text/plain", callback, nil); !IsIntegrityError(err) {
+		t.Errorf("expected an integrity error %v", err)
+	}
+	context := &UploadContext{Key: "a.txt", UploadId: "upload"}
+	context.addPart(part{1, `"etag"`, 5, strconv.FormatUint(CRC64([]byte("world")), 10)})
+	if _, err := client.CompleteMultipartWithCallback(context, callback, nil); !IsIntegrityError(err) {
+		t.Errorf("expected an integrity error %v", err)
+	}
+	client.SetCRCCheck(false)
+	if _, err := client.CompleteMultipartWithCallback(context, callback, nil); err != nil {
+		t.Errorf("the crc check should be skipped %v", err)
+	}
+}
+
+func TestCallbackVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	fetched := 0
+	var received map[string]string
+	verifier := NewCallbackVerifier(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received, _ = ParseCallbackBody(r, body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Status":"OK"}`))
+	}))
+	verifier.FetchPublicKey = func(url string) ([]byte, error) {
+		fetched++
+		return publicKey, nil
+	}
+	server := httptest.NewServer(verifier)
+	defer server.Close()
+
+	post := func(keyUrl, signedBody, body string) int {
+		digest := md5.Sum([]byte("/callback?id=1\n" + signedBody))
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.MD5, digest[:])
+		req, _ := http.NewRequest("POST", server.URL+"/callback?id=1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", b64.EncodeToString(signature))
+		req.Header.Set("x-oss-pub-key-url", b64.EncodeToString([]byte(keyUrl)))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("cant post callback %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	keyUrl := "https://gosspublic.alicdn.com/callback_pub_key_v1.pem"
+	body := "bucket=bucket&object=a%2Fb.txt&user=me"
+	if status := post(keyUrl, body, body); status != http.StatusOK {
+		t.Errorf("valid callback should be accepted, actual %d", status)
+	}
+	if received["object"] != "a/b.txt" || received["user"] != "me" {
+		t.Errorf("wrong callback body %v", received)
+	}
+	if status := post(keyUrl, body, body+"&size=1"); status != http.StatusBadRequest {
+		t.Errorf("tampered callback should be rejected, actual %d", status)
+	}
+	if status := post("https://example.com/callback_pub_key_v1.pem", body, body); status != http.StatusBadRequest {
+		t.Errorf("untrusted public key should be rejected, actual %d", status)
+	}
+	if fetched != 1 {
+		t.Errorf("public key should be cached, fetched %d times", fetched)
+	}
+}
@@ -0,0 +1,89 @@
+package oss
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	const etag = `"5B3C1A2E053D763E1B002CC607C5A0FE"`
+	modified := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		if r.Method == "PUT" {
+			if r.Header["x-oss-forbid-overwrite"] != nil {
+				return newErrorResponse(http.StatusConflict, nil, "<Error><Code>FileAlreadyExists</Code></Error>")
+			}
+			if r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != etag {
+				return newErrorResponse(http.StatusPreconditionFailed, nil, "<Error><Code>PreconditionFailed</Code></Error>")
+			}
+			return &http.Response{StatusCode: http.StatusOK}
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			return newErrorResponse(http.StatusNotModified, nil, "")
+		}
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+			return newErrorResponse(http.StatusNotModified, nil, "")
+		}
+		if since, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && modified.After(since) {
+			return newErrorResponse(http.StatusPreconditionFailed, nil, "")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": {etag}}}
+	}))
+
+	if _, err := client.GetObjectMetadata("a.txt", IfNoneMatch(etag)); !IsNotModified(err) || IsPreconditionFailed(err) {
+		t.Errorf("wrong error %v", err)
+	}
+	if _, err := client.GetObject("a.txt", IfModifiedSince(modified.In(time.Local))); !IsNotModified(err) {
+		t.Errorf("wrong error %v", err)
+	}
+	if _, err := client.GetObject("a.txt", IfModifiedSince(modified.Add(-time.Hour))); err != nil {
+		t.Errorf("the modified object should be returned %v", err)
+	}
+	if _, _, err := client.GetObjectRange("a.txt", 0, 1, IfUnmodifiedSince(modified.Add(-time.Hour))); !IsPreconditionFailed(err) || IsNotModified(err) {
+		t.Errorf("wrong error %v", err)
+	}
+	if err := client.PutObject("a.txt", []byte("hello"), "text/plain", IfMatch(`"other"`)); !IsPreconditionFailed(err) {
+		t.Errorf("wrong error %v", err)
+	}
+	if err := client.PutObject("a.txt", []byte("hello"), "text/plain", IfMatch(etag)); err != nil {
+		t.Errorf("the matching put should succeed %v", err)
+	}
+	if err := client.PutObject("a.txt", []byte("hello"), "text/plain", ForbidOverwrite()); !IsAlreadyExists(err) || IsPreconditionFailed(err) {
+		t.Errorf("wrong error %v", err)
+	}
+}
+
+func TestCopyForbidOverwrite(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		query := r.URL.Query()
+		if _, ok := query["uploads"]; ok {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>")),
+			}
+		}
+		if query.Get("partNumber") != "" {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Range": {"bytes 0-9/10"}},
+				Body:       ioutil.NopCloser(strings.NewReader("<CopyPartResult><ETag>etag</ETag></CopyPartResult>")),
+			}
+		}
+		// the target is created between the init and the complete
+		if r.Method == "POST" && r.Header["x-oss-forbid-overwrite"] != nil {
+			return newErrorResponse(http.StatusConflict, nil, "<Error><Code>FileAlreadyExists</Code></Error>")
+		}
+		return &http.Response{StatusCode: http.StatusOK}
+	}))
+	if err := client.Copy("", "a.txt", "b.txt", "text/plain", 0, ForbidOverwrite()); !IsAlreadyExists(err) {
+		t.Errorf("wrong error %v", err)
+	}
+	if err := client.Copy("", "a.txt", "b.txt", "text/plain", 0); err != nil {
+		t.Errorf("the copy should overwrite %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package oss
+
+import (
+	"hash"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// CRC64 is the x-oss-hash-crc64ecma checksum of data
+func CRC64(data []byte) uint64 {
+	return crc64.Checksum(data, crcTable)
+}
+
+// CRC64Combine returns the CRC64 of the concatenation of two blocks
+// from their checksums crc1 and crc2 and the length of the second block
+func CRC64Combine(crc1, crc2, len2 uint64) uint64 {
+	if len2 == 0 {
+		return crc1
+	}
+	// the operators appending one, two, four... zero bits to a crc
+	var even, odd [64]uint64
+	odd[0] = crc64.ECMA
+	row := uint64(1)
+	for n := 1; n < 64; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	gf2MatrixSquare(even[:], odd[:])
+	gf2MatrixSquare(odd[:], even[:])
+
+	// append len2 zero bytes to crc1
+	for {
+		gf2MatrixSquare(even[:], odd[:])
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(even[:], crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+		gf2MatrixSquare(odd[:], even[:])
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(odd[:], crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+func gf2MatrixTimes(mat []uint64, vec uint64) uint64 {
+	var sum uint64
+	for i := 0; vec != 0; i++ {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+		vec >>= 1
+	}
+	return sum
+}
+
+func gf2MatrixSquare(square, mat []uint64) {
+	for n := range square {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
+
+// SetCRCCheck turns the CRC64 verification of the transfers on or off, it's on by default
+func (api *OssApi) SetCRCCheck(enabled bool) {
+	api.skipCRC = !enabled
+}
+
+// checkCRC compares crc with the x-oss-hash-crc64ecma of the response if oss sent one
+func (api *OssApi) checkCRC(req *request, hresp *http.Response, crc uint64) error {
+	if api.skipCRC {
+		return nil
+	}
+	header := hresp.Header.Get("x-oss-hash-crc64ecma")
+	if header == "" {
+		return nil
+	}
+	serverCRC, err := strconv.ParseUint(header, 10, 64)
+	if err != nil || serverCRC != crc {
+		return &IntegrityError{
+			Operation: req.operation,
+			Key:       req.object,
+			ClientCRC: crc,
+			ServerCRC: header,
+			RequestId: hresp.Header.Get("x-oss-request-id"),
+		}
+	}
+	return nil
+}
+
+// partsCRC combines the crc of the parts, false if any of them is unknown
+func partsCRC(parts partSlice) (uint64, bool) {
+	parts = append(partSlice(nil), parts...)
+	sort.Sort(parts)
+	var crc uint64
+	for _, part := range parts {
+		if part.CRC64 == "" {
+			return 0, false
+		}
+		partCRC, err := strconv.ParseUint(part.CRC64, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		crc = CRC64Combine(crc, partCRC, uint64(part.Size))
+	}
+	return crc, true
+}
+
+// crcReadCloser checks the crc of the response body at EOF
+type crcReadCloser struct {
+	io.ReadCloser
+	hash hash.Hash64
+	api  *OssApi
+	req  *request
+	resp *http.Response
+}
+
+func (r *crcReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if crcErr := r.api.checkCRC(r.req, r.resp, r.hash.Sum64()); crcErr != nil {
+			return n, crcErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,153 @@
+package oss
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCRC64(t *testing.T) {
+	// the CRC-64/XZ check value
+	if crc := CRC64([]byte("123456789")); crc != 0x995dc9bbdf1939fa {
+		t.Errorf("wrong crc64 %x", crc)
+	}
+	a, b := []byte("hello "), []byte("world")
+	if combined := CRC64Combine(CRC64(a), CRC64(b), uint64(len(b))); combined != CRC64([]byte("hello world")) {
+		t.Errorf("wrong combined crc64 %d", combined)
+	}
+	if combined := CRC64Combine(0, CRC64(b), uint64(len(b))); combined != CRC64(b) {
+		t.Errorf("combining with an empty block should keep the crc %d", combined)
+	}
+}
+
+func crcHeader(data []byte) http.Header {
+	return http.Header{"X-Oss-Hash-Crc64ecma": {strconv.FormatUint(CRC64(data), 10)}}
+}
+
+func TestPutObjectCRC(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var stored []byte
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		// the server stores the contents corrupted
+		stored = append([]byte("x"), body[1:]...)
+		return &http.Response{StatusCode: http.StatusOK, Header: crcHeader(stored)}
+	}))
+	err := client.PutObject("a.txt", []byte("hello"), "text/plain")
+	if !IsIntegrityError(err) || !IsRetryable(err) {
+		t.Errorf("wrong error %v", err)
+	}
+	client.SetCRCCheck(false)
+	if err := client.PutObject("a.txt", []byte("hello"), "text/plain"); err != nil {
+		t.Errorf("the crc check should be off %v", err)
+	}
+}
+
+func TestGetObjectCRC(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	contents := []byte("hello world")
+	header := crcHeader(contents)
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		status := http.StatusOK
+		if _, ok := r.Header["Range"]; ok {
+			status = http.StatusPartialContent
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: ioutil.NopCloser(strings.NewReader("hello word!"))}
+	}))
+	if _, err := client.GetObject("a.txt"); !IsIntegrityError(err) {
+		t.Errorf("wrong error %v", err)
+	}
+	r, _, err := client.GetObjectRange("a.txt", 0, 10)
+	if err != nil {
+		t.Fatalf("cant get object range %v", err)
+	}
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Errorf("the ranges are not checked %v", err)
+	}
+
+	header = crcHeader([]byte("hello word!"))
+	if data, err := client.GetObject("a.txt"); err != nil || string(data) != "hello word!" {
+		t.Errorf("cant get object %s %v", data, err)
+	}
+}
+
+func TestMultipartCRC(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	completeHeader := crcHeader([]byte("part1part2"))
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		if r.URL.Query().Get("partNumber") != "" {
+			return &http.Response{StatusCode: http.StatusOK, Header: crcHeader(body)}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: completeHeader}
+	}))
+	context := &UploadContext{Key: "a.txt", UploadId: "upload"}
+	// the parts are combined in the order of their numbers
+	for _, p := range []struct {
+		number   int
+		contents string
+	}{{2, "part2"}, {1, "part1"}} {
+		if err := client.UploadMultipart(context, []byte(p.contents), p.number); err != nil {
+			t.Fatalf("cant upload part %v", err)
+		}
+	}
+	if err := client.CompleteMultipart(context); err != nil {
+		t.Errorf("cant complete the upload %v", err)
+	}
+	completeHeader = crcHeader([]byte("part2part1"))
+	if err := client.CompleteMultipart(context); !IsIntegrityError(err) {
+		t.Errorf("wrong error %v", err)
+	}
+}
+
+func TestAppendObjectCRC(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var object []byte
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		if _, ok := r.URL.Query()["append"]; !ok || r.URL.Query().Get("position") != strconv.Itoa(len(object)) {
+			return &http.Response{StatusCode: http.StatusConflict}
+		}
+		object = append(object, body...)
+		header := crcHeader(object)
+		header.Set("x-oss-next-append-position", strconv.Itoa(len(object)))
+		return &http.Response{StatusCode: http.StatusOK, Header: header}
+	}))
+	position, crc, err := client.AppendObject("a.txt", []byte("hello "), 0, 0)
+	if err != nil {
+		t.Fatalf("cant append object %v", err)
+	}
+	position, crc, err = client.AppendObject("a.txt", []byte("world"), position, crc)
+	if err != nil {
+		t.Fatalf("cant append object %v", err)
+	}
+	if position != 11 || crc != CRC64([]byte("hello world")) {
+		t.Errorf("wrong position %d or crc %d", position, crc)
+	}
+	if _, _, err := client.AppendObject("a.txt", []byte("!"), position, 0); !IsIntegrityError(err) {
+		t.Errorf("wrong error %v", err)
+	}
+}
+
+func TestMultipartCopyPartCRC(t *testing.T) {
+	client, server := newFakeClient(t)
+	source := []byte(strings.Repeat("s", 1000))
+	client.PutObject("source.txt", source, "text/plain")
+	context, err := client.InitMultipartUpload("target.txt", "text/plain")
+	if err != nil {
+		t.Fatalf("cant init multi upload %v", err)
+	}
+	uploaded := []byte(strings.Repeat("u", 100*1024))
+	if err := client.UploadMultipart(context, uploaded, 1); err != nil {
+		t.Fatalf("cant upload part %v", err)
+	}
+	// the whole object copy has no Content-Range
+	if _, err := client.UploadCopyMultipart(context, "", "source.txt", -1, -1, 2); err != nil {
+		t.Fatalf("cant copy part %v", err)
+	}
+	if err := client.CompleteMultipart(context); err != nil {
+		t.Fatalf("cant complete the upload %v", err)
+	}
+	if data, _, _ := server.Object("target.txt"); string(data) != string(uploaded)+string(source) {
+		t.Errorf("wrong completed object of %d bytes", len(data))
+	}
+}
@@ -0,0 +1,278 @@
+package oss
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials signs the requests, SecurityToken and Expiration are only
+// set for the STS temporary credentials
+type Credentials struct {
+	AccessKeyId, AccessKeySecret, SecurityToken string
+	Expiration                                  time.Time
+}
+
+// CredentialsProvider is asked for new credentials by the client
+// for the first request and again shortly before the cached ones expire.
+// Credentials without Expiration are never refreshed.
+type CredentialsProvider interface {
+	Retrieve() (*Credentials, error)
+}
+
+// CredentialsRefresher fetches a new set of STS credentials,
+// e.g. by calling the sts AssumeRole api
+type CredentialsRefresher func() (*Credentials, error)
+
+func (refresh CredentialsRefresher) Retrieve() (*Credentials, error) {
+	return refresh()
+}
+
+type StaticCredentialsProvider struct {
+	Credentials
+}
+
+func NewStaticCredentialsProvider(accessKeyId, accessKeySecret, securityToken string) *StaticCredentialsProvider {
+	return &StaticCredentialsProvider{Credentials{
+		AccessKeyId:     accessKeyId,
+		AccessKeySecret: accessKeySecret,
+		SecurityToken:   securityToken,
+	}}
+}
+
+func (p *StaticCredentialsProvider) Retrieve() (*Credentials, error) {
+	if p.AccessKeyId == "" || p.AccessKeySecret == "" {
+		return nil, errors.New("oss: static credentials are empty")
+	}
+	creds := p.Credentials
+	return &creds, nil
+}
+
+// EnvCredentialsProvider reads OSS_ACCESS_KEY, OSS_SECRET_KEY
+// and the optional OSS_SECURITY_TOKEN
+type EnvCredentialsProvider struct{}
+
+func (EnvCredentialsProvider) Retrieve() (*Credentials, error) {
+	creds := &Credentials{
+		AccessKeyId:     os.Getenv("OSS_ACCESS_KEY"),
+		AccessKeySecret: os.Getenv("OSS_SECRET_KEY"),
+		SecurityToken:   os.Getenv("OSS_SECURITY_TOKEN"),
+	}
+	if creds.AccessKeyId == "" || creds.AccessKeySecret == "" {
+		return nil, errors.New("oss: OSS_ACCESS_KEY or OSS_SECRET_KEY not set")
+	}
+	return creds, nil
+}
+
+// ProfileCredentialsProvider reads the ini styled alibaba cloud credentials file
+//
+//	[default]
+//	access_key_id = foo
+//	access_key_secret = bar
+//	security_token = optional
+//
+// Filename defaults to $ALIBABA_CLOUD_CREDENTIALS_FILE or ~/.alibabacloud/credentials,
+// Profile defaults to $ALIBABA_CLOUD_PROFILE or "default".
+type ProfileCredentialsProvider struct {
+	Filename, Profile string
+}
+
+func (p *ProfileCredentialsProvider) Retrieve() (*Credentials, error) {
+	filename := p.Filename
+	if filename == "" {
+		filename = os.Getenv("ALIBABA_CLOUD_CREDENTIALS_FILE")
+	}
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		filename = filepath.Join(home, ".alibabacloud", "credentials")
+	}
+	profile := p.Profile
+	if profile == "" {
+		profile = os.Getenv("ALIBABA_CLOUD_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		if i := strings.Index(line, "="); i > 0 {
+			values[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	creds := &Credentials{
+		AccessKeyId:     values["access_key_id"],
+		AccessKeySecret: values["access_key_secret"],
+		SecurityToken:   values["security_token"],
+	}
+	if creds.AccessKeyId == "" || creds.AccessKeySecret == "" {
+		return nil, fmt.Errorf("oss: no access key in profile %q of %s", profile, filename)
+	}
+	return creds, nil
+}
+
+const ecsMetadataEndpoint = "http://100.100.100.200/latest/meta-data/ram/security-credentials/"
+
+// ECSRAMRoleCredentialsProvider fetches the STS credentials of the RAM role
+// attached to the ECS instance from the metadata service.
+// The role name is looked up from the metadata service if RoleName is empty.
+type ECSRAMRoleCredentialsProvider struct {
+	RoleName string
+	Endpoint string       // defaults to the ECS metadata service
+	Client   *http.Client // defaults to a client with a 5 seconds timeout
+}
+
+func (p *ECSRAMRoleCredentialsProvider) get(u string) ([]byte, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	hresp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer hresp.Body.Close()
+	body, err := ioutil.ReadAll(hresp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if hresp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oss: ecs metadata %s returns %s", u, hresp.Status)
+	}
+	return body, nil
+}
+
+func (p *ECSRAMRoleCredentialsProvider) Retrieve() (*Credentials, error) {
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = ecsMetadataEndpoint
+	}
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	roleName := p.RoleName
+	if roleName == "" {
+		body, err := p.get(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		roleName = strings.TrimSpace(string(body))
+	}
+	body, err := p.get(endpoint + roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Code            string
+		AccessKeyId     string
+		AccessKeySecret string
+		SecurityToken   string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != "Success" {
+		return nil, fmt.Errorf("oss: ecs metadata returns code %q for role %s", resp.Code, roleName)
+	}
+	return &Credentials{
+		AccessKeyId:     resp.AccessKeyId,
+		AccessKeySecret: resp.AccessKeySecret,
+		SecurityToken:   resp.SecurityToken,
+		Expiration:      resp.Expiration,
+	}, nil
+}
+
+// ChainCredentialsProvider returns the credentials of the first provider that succeeds
+type ChainCredentialsProvider []CredentialsProvider
+
+func (chain ChainCredentialsProvider) Retrieve() (*Credentials, error) {
+	var errs []string
+	for _, provider := range chain {
+		creds, err := provider.Retrieve()
+		if err == nil {
+			return creds, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, errors.New("oss: no valid credentials in chain: " + strings.Join(errs, "; "))
+}
+
+// DefaultCredentialsProvider tries the environment variables and the profile file,
+// then the ECS RAM role named by $ALIBABA_CLOUD_ECS_METADATA if it is set.
+func DefaultCredentialsProvider() CredentialsProvider {
+	chain := ChainCredentialsProvider{
+		EnvCredentialsProvider{},
+		&ProfileCredentialsProvider{},
+	}
+	if roleName := os.Getenv("ALIBABA_CLOUD_ECS_METADATA"); roleName != "" {
+		chain = append(chain, &ECSRAMRoleCredentialsProvider{RoleName: roleName})
+	}
+	return chain
+}
+
+// the credentials are refreshed this long before they expire
+const credentialsRefreshWindow = 5 * time.Minute
+
+type credentialsCache struct {
+	sync.Mutex
+	credentials *Credentials
+	provider    CredentialsProvider
+}
+
+func (creds *Credentials) expiresWithin(d time.Duration) bool {
+	return !creds.Expiration.IsZero() && time.Now().Add(d).After(creds.Expiration)
+}
+
+func (cache *credentialsCache) get() (*Credentials, error) {
+	cache.Lock()
+	defer cache.Unlock()
+	if cache.credentials != nil && !cache.credentials.expiresWithin(credentialsRefreshWindow) {
+		return cache.credentials, nil
+	}
+	creds, err := cache.provider.Retrieve()
+	if err != nil {
+		// keep using the old credentials until they are really expired
+		if cache.credentials != nil && !cache.credentials.expiresWithin(0) {
+			return cache.credentials, nil
+		}
+		return nil, err
+	}
+	cache.credentials = creds
+	return creds, nil
+}
@@ -0,0 +1,217 @@
+package oss
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityTokenHeader(t *testing.T) {
+	client := NewWithSecurityToken("oss-cn-hangzhou", "STS.id", "secret", "token", "bucket", false)
+	req := &request{
+		method: "GET",
+		object: "object",
+	}
+	if err := client.prepare(req); err != nil {
+		t.Fatalf("cant prepare request %v", err)
+	}
+	if token := req.headers["x-oss-security-token"]; len(token) != 1 || token[0] != "token" {
+		t.Errorf("wrong security token header expected %s, actual %v", "token", token)
+	}
+	if auth := req.headers.Get("Authorization"); !strings.HasPrefix(auth, "OSS STS.id:") {
+		t.Errorf("wrong authorization header %s", auth)
+	}
+}
+
+func TestPresignedUrlSecurityToken(t *testing.T) {
+	client := NewWithSecurityToken("oss-cn-hangzhou", "STS.id", "secret", "token", "bucket", false)
+	u, err := url.Parse(client.GeneratePresignedUrl("object", 60))
+	if err != nil {
+		t.Fatalf("cant parse presigned url %v", err)
+	}
+	query := u.Query()
+	if query.Get("security-token") != "token" {
+		t.Errorf("wrong security-token param %s", query.Get("security-token"))
+	}
+	if query.Get("OSSAccessKeyId") != "STS.id" {
+		t.Errorf("wrong OSSAccessKeyId param %s", query.Get("OSSAccessKeyId"))
+	}
+}
+
+func TestCredentialsRefresh(t *testing.T) {
+	var calls int
+	var refreshErr error
+	client := NewWithRefresher("oss-cn-hangzhou", "bucket", false, func() (*Credentials, error) {
+		if refreshErr != nil {
+			return nil, refreshErr
+		}
+		calls++
+		return &Credentials{
+			AccessKeyId:     "STS.id" + strings.Repeat("x", calls),
+			AccessKeySecret: "secret",
+			SecurityToken:   "token",
+			Expiration:      time.Now().Add(time.Hour),
+		}, nil
+	})
+
+	creds, err := client.credentials.get()
+	if err != nil {
+		t.Fatalf("cant get credentials %v", err)
+	}
+	creds, _ = client.credentials.get()
+	if calls != 1 || creds.AccessKeyId != "STS.idx" {
+		t.Errorf("credentials should be cached, refresh called %d times", calls)
+	}
+
+	creds.Expiration = time.Now().Add(time.Minute)
+	creds, _ = client.credentials.get()
+	if calls != 2 || creds.AccessKeyId != "STS.idxx" {
+		t.Errorf("credentials should be refreshed before expiry, refresh called %d times", calls)
+	}
+
+	refreshErr = errors.New("sts unavailable")
+	creds.Expiration = time.Now().Add(time.Minute)
+	if _, err = client.credentials.get(); err != nil {
+		t.Errorf("unexpired credentials should be kept when refresh fails %v", err)
+	}
+	creds.Expiration = time.Now().Add(-time.Minute)
+	if _, err = client.credentials.get(); err != refreshErr {
+		t.Errorf("expired credentials should not be used %v", err)
+	}
+}
+
+func TestEnvCredentialsProvider(t *testing.T) {
+	t.Setenv("OSS_ACCESS_KEY", "envid")
+	t.Setenv("OSS_SECRET_KEY", "envsecret")
+	t.Setenv("OSS_SECURITY_TOKEN", "")
+	creds, err := EnvCredentialsProvider{}.Retrieve()
+	if err != nil {
+		t.Fatalf("cant retrieve env credentials %v", err)
+	}
+	if creds.AccessKeyId != "envid" || creds.AccessKeySecret != "envsecret" || creds.SecurityToken != "" {
+		t.Errorf("wrong env credentials %#v", creds)
+	}
+
+	t.Setenv("OSS_SECRET_KEY", "")
+	if _, err = (EnvCredentialsProvider{}).Retrieve(); err == nil {
+		t.Errorf("missing OSS_SECRET_KEY should fail")
+	}
+}
+
+func TestProfileCredentialsProvider(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "credentials")
+	err := ioutil.WriteFile(filename, []byte(`# comment
+[default]
+access_key_id = defaultid
+access_key_secret = defaultsecret
+
+[sts]
+access_key_id=STS.id
+access_key_secret=stssecret
+security_token=token
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := (&ProfileCredentialsProvider{Filename: filename}).Retrieve()
+	if err != nil {
+		t.Fatalf("cant retrieve profile credentials %v", err)
+	}
+	if creds.AccessKeyId != "defaultid" || creds.AccessKeySecret != "defaultsecret" {
+		t.Errorf("wrong default profile credentials %#v", creds)
+	}
+
+	creds, err = (&ProfileCredentialsProvider{Filename: filename, Profile: "sts"}).Retrieve()
+	if err != nil {
+		t.Fatalf("cant retrieve profile credentials %v", err)
+	}
+	if creds.AccessKeyId != "STS.id" || creds.SecurityToken != "token" {
+		t.Errorf("wrong sts profile credentials %#v", creds)
+	}
+
+	if _, err = (&ProfileCredentialsProvider{Filename: filename, Profile: "missing"}).Retrieve(); err == nil {
+		t.Errorf("missing profile should fail")
+	}
+}
+
+func TestECSRAMRoleCredentialsProvider(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/meta-data/ram/security-credentials/":
+			fmt.Fprint(w, "EcsRole")
+		case "/latest/meta-data/ram/security-credentials/EcsRole":
+			fmt.Fprintf(w, `{"AccessKeyId":"STS.ecs","AccessKeySecret":"ecssecret","Expiration":"%s","SecurityToken":"ecstoken","LastUpdated":"2023-01-01T00:00:00Z","Code":"Success"}`,
+				expiration.Format(time.RFC3339))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := &ECSRAMRoleCredentialsProvider{Endpoint: server.URL + "/latest/meta-data/ram/security-credentials/"}
+	creds, err := provider.Retrieve()
+	if err != nil {
+		t.Fatalf("cant retrieve ecs credentials %v", err)
+	}
+	if creds.AccessKeyId != "STS.ecs" || creds.AccessKeySecret != "ecssecret" || creds.SecurityToken != "ecstoken" {
+		t.Errorf("wrong ecs credentials %#v", creds)
+	}
+	if !creds.Expiration.Equal(expiration) {
+		t.Errorf("wrong ecs credentials expiration expected %s, actual %s", expiration, creds.Expiration)
+	}
+
+	provider.RoleName = "OtherRole"
+	if _, err = provider.Retrieve(); err == nil {
+		t.Errorf("unknown role should fail")
+	}
+}
+
+func TestChainCredentialsProvider(t *testing.T) {
+	t.Setenv("OSS_ACCESS_KEY", "")
+	chain := ChainCredentialsProvider{
+		EnvCredentialsProvider{},
+		&ProfileCredentialsProvider{Filename: filepath.Join(t.TempDir(), "missing")},
+		NewStaticCredentialsProvider("staticid", "staticsecret", ""),
+	}
+	creds, err := chain.Retrieve()
+	if err != nil {
+		t.Fatalf("cant retrieve chain credentials %v", err)
+	}
+	if creds.AccessKeyId != "staticid" {
+		t.Errorf("wrong chain credentials %#v", creds)
+	}
+
+	if _, err = chain[:2].Retrieve(); err == nil {
+		t.Errorf("chain without valid credentials should fail")
+	}
+
+	t.Setenv("OSS_ACCESS_KEY", "envid")
+	t.Setenv("OSS_SECRET_KEY", "envsecret")
+	creds, _ = chain.Retrieve()
+	if creds.AccessKeyId != "envid" {
+		t.Errorf("env credentials should come first %#v", creds)
+	}
+}
+
+func TestGeneratePresignedUrlLogsError(t *testing.T) {
+	client := NewWithRefresher("oss-cn-hangzhou", "bucket", false, func() (*Credentials, error) {
+		return nil, errors.New("no credentials")
+	})
+	logger := &recordingLogger{}
+	client.SetLogger(logger)
+	if u := client.GeneratePresignedUrl("object", 60); u != "" {
+		t.Errorf("expected an empty url %s", u)
+	}
+	if !strings.Contains(strings.Join(logger.lines, "\n"), "no credentials") {
+		t.Errorf("the error should be logged %v", logger.lines)
+	}
+}
@@ -0,0 +1,535 @@
+package oss
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// ----------------------------------------------------------------------------
+// client side envelope encryption
+// https://help.aliyun.com/zh/oss/user-guide/client-side-encryption
+//
+// Every object is encrypted with its own random data key and IV, both are wrapped
+// by the master key and stored in the x-oss-meta-client-side-encryption-* headers.
+// The AES/CTR objects wrapped by RSA can be read and written by the official sdks.
+
+// the content encryption algorithms
+const (
+	CryptoAESCTR = "AES/CTR/NoPadding"
+	CryptoAESGCM = "AES/GCM/NoPadding" // no multipart upload nor range GET
+)
+
+// the master key wrap algorithms
+const (
+	CryptoWrapRSA = "RSA/NONE/PKCS1Padding"
+	CryptoWrapAES = "AES/KeyWrap" // RFC 3394
+)
+
+const (
+	cryptoMetaPrefix        = "x-oss-meta-client-side-encryption-"
+	cryptoKey               = cryptoMetaPrefix + "key"
+	cryptoStart             = cryptoMetaPrefix + "start"
+	cryptoCekAlg            = cryptoMetaPrefix + "cek-alg"
+	cryptoWrapAlg           = cryptoMetaPrefix + "wrap-alg"
+	cryptoMatDesc           = cryptoMetaPrefix + "matdesc"
+	cryptoUnencryptedLength = cryptoMetaPrefix + "unencrypted-content-length"
+	cryptoUnencryptedMD5    = cryptoMetaPrefix + "unencrypted-content-md5"
+	cryptoDataSize          = cryptoMetaPrefix + "data-size"
+	cryptoPartSize          = cryptoMetaPrefix + "part-size"
+	cryptoDataKeySize       = 32
+	cryptoBlockSize         = aes.BlockSize
+)
+
+const cryptoUnsupported = "oss: %s is not supported with %s"
+
+// MasterKey wraps the data keys of the objects
+type MasterKey interface {
+	WrapAlgorithm() string
+	// MatDesc is the json description stored with the objects to find the key again
+	MatDesc() string
+	Wrap(plain []byte) ([]byte, error)
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+func encodeMatDesc(matDesc map[string]string) (string, error) {
+	if len(matDesc) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(matDesc)
+	return string(data), err
+}
+
+type rsaMasterKey struct {
+	publicKey  *rsa.PublicKey
+	privateKey *rsa.PrivateKey
+	matDesc    string
+}
+
+// NewRSAMasterKey wraps the data keys with PKCS#1 v1.5,
+// privateKey may be nil if the client only uploads
+func NewRSAMasterKey(publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey, matDesc map[string]string) (MasterKey, error) {
+	if publicKey == nil && privateKey != nil {
+		publicKey = &privateKey.PublicKey
+	}
+	if publicKey == nil {
+		return nil, errors.New("oss: missing rsa master key")
+	}
+	desc, err := encodeMatDesc(matDesc)
+	if err != nil {
+		return nil, err
+	}
+	return &rsaMasterKey{publicKey, privateKey, desc}, nil
+}
+
+func (k *rsaMasterKey) WrapAlgorithm() string { return CryptoWrapRSA }
+func (k *rsaMasterKey) MatDesc() string       { return k.matDesc }
+
+func (k *rsaMasterKey) Wrap(plain []byte) ([]byte, error) {
+	return rsa.EncryptPKCS1v15(rand.Reader, k.publicKey, plain)
+}
+
+func (k *rsaMasterKey) Unwrap(wrapped []byte) ([]byte, error) {
+	if k.privateKey == nil {
+		return nil, errors.New("oss: the rsa master key has no private key")
+	}
+	return rsa.DecryptPKCS1v15(rand.Reader, k.privateKey, wrapped)
+}
+
+type aesMasterKey struct {
+	block   cipher.Block
+	matDesc string
+}
+
+// NewAESMasterKey wraps the data keys with the RFC 3394 AES key wrap,
+// key is 16, 24 or 32 bytes
+func NewAESMasterKey(key []byte, matDesc map[string]string) (MasterKey, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := encodeMatDesc(matDesc)
+	if err != nil {
+		return nil, err
+	}
+	return &aesMasterKey{block, desc}, nil
+}
+
+func (k *aesMasterKey) WrapAlgorithm() string { return CryptoWrapAES }
+func (k *aesMasterKey) MatDesc() string       { return k.matDesc }
+
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+func (k *aesMasterKey) Wrap(plain []byte) ([]byte, error) {
+	if len(plain) < 16 || len(plain)%8 != 0 {
+		return nil, errors.New("oss: the aes key wrap needs a multiple of 8 bytes")
+	}
+	n := len(plain) / 8
+	wrapped := make([]byte, 8+len(plain))
+	copy(wrapped, keyWrapIV)
+	copy(wrapped[8:], plain)
+	var b [16]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b[:8], wrapped[:8])
+			copy(b[8:], wrapped[8*i:8*i+8])
+			k.block.Encrypt(b[:], b[:])
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(wrapped[:8], binary.BigEndian.Uint64(b[:8])^t)
+			copy(wrapped[8*i:8*i+8], b[8:])
+		}
+	}
+	return wrapped, nil
+}
+
+func (k *aesMasterKey) Unwrap(wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, errors.New("oss: invalid aes wrapped key")
+	}
+	n := len(wrapped)/8 - 1
+	plain := make([]byte, len(wrapped))
+	copy(plain, wrapped)
+	var b [16]byte
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(plain[:8])^t)
+			copy(b[8:], plain[8*i:8*i+8])
+			k.block.Decrypt(b[:], b[:])
+			copy(plain[:8], b[:8])
+			copy(plain[8*i:8*i+8], b[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(plain[:8], keyWrapIV) != 1 {
+		return nil, errors.New("oss: the aes wrapped key doesn't match the master key")
+	}
+	return plain[8:], nil
+}
+
+// cipherData is the data key and the IV of an object
+type cipherData struct {
+	algorithm string
+	key, iv   []byte
+	master    MasterKey
+}
+
+func newCipherData(algorithm string, master MasterKey) (*cipherData, error) {
+	data := &cipherData{algorithm: algorithm, key: make([]byte, cryptoDataKeySize), iv: make([]byte, cryptoBlockSize), master: master}
+	if _, err := io.ReadFull(rand.Reader, data.key); err != nil {
+		return nil, err
+	}
+	// the counter in the last 8 bytes starts below 2^32 like in the official sdks,
+	// so that seeking never carries into the nonce
+	if _, err := io.ReadFull(rand.Reader, data.iv); err != nil {
+		return nil, err
+	}
+	copy(data.iv[8:12], []byte{0, 0, 0, 0})
+	return data, nil
+}
+
+// ivAt returns the CTR IV of the block at offset, which is aligned to cryptoBlockSize
+func (data *cipherData) ivAt(offset int64) []byte {
+	iv := append([]byte(nil), data.iv...)
+	counter := binary.BigEndian.Uint64(iv[8:]) + uint64(offset/cryptoBlockSize)
+	binary.BigEndian.PutUint64(iv[8:], counter)
+	return iv
+}
+
+func (data *cipherData) stream(offset int64) (cipher.Stream, error) {
+	block, err := aes.NewCipher(data.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, data.ivAt(offset)), nil
+}
+
+func (data *cipherData) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(data.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, len(data.iv))
+}
+
+// encrypt encrypts contents found at offset of the object
+func (data *cipherData) encrypt(contents []byte, offset int64) ([]byte, error) {
+	if data.algorithm == CryptoAESGCM {
+		aead, err := data.gcm()
+		if err != nil {
+			return nil, err
+		}
+		return aead.Seal(nil, data.iv, contents, nil), nil
+	}
+	stream, err := data.stream(offset)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := make([]byte, len(contents))
+	stream.XORKeyStream(encrypted, contents)
+	return encrypted, nil
+}
+
+// options returns the envelope headers of the object
+func (data *cipherData) options() ([]Option, error) {
+	wrappedKey, err := data.master.Wrap(data.key)
+	if err != nil {
+		return nil, err
+	}
+	wrappedIV, err := data.master.Wrap(data.iv)
+	if err != nil {
+		return nil, err
+	}
+	options := []Option{
+		WithHeader(cryptoKey, b64.EncodeToString(wrappedKey)),
+		WithHeader(cryptoStart, b64.EncodeToString(wrappedIV)),
+		WithHeader(cryptoCekAlg, data.algorithm),
+		WithHeader(cryptoWrapAlg, data.master.WrapAlgorithm()),
+	}
+	if matDesc := data.master.MatDesc(); matDesc != "" {
+		options = append(options, WithHeader(cryptoMatDesc, matDesc))
+	}
+	return options, nil
+}
+
+// CryptoClient encrypts the objects before they are uploaded by api
+// and decrypts them after they are downloaded
+type CryptoClient struct {
+	api       *OssApi
+	master    MasterKey
+	algorithm string
+	keys      map[string]MasterKey
+}
+
+// NewCryptoClient encrypts the new objects with algorithm and master,
+// the additional keys decrypt the objects wrapped by other master keys of matching MatDesc
+func NewCryptoClient(api *OssApi, algorithm string, master MasterKey, keys ...MasterKey) (*CryptoClient, error) {
+	if algorithm != CryptoAESCTR && algorithm != CryptoAESGCM {
+		return nil, fmt.Errorf("oss: unknown content encryption algorithm %s", algorithm)
+	}
+	client := &CryptoClient{api, master, algorithm, make(map[string]MasterKey)}
+	for _, key := range keys {
+		client.keys[key.MatDesc()] = key
+	}
+	return client, nil
+}
+
+func (client *CryptoClient) PutObject(object string, contents []byte, contentType string, options ...Option) error {
+	data, err := newCipherData(client.algorithm, client.master)
+	if err != nil {
+		return err
+	}
+	encrypted, err := data.encrypt(contents, 0)
+	if err != nil {
+		return err
+	}
+	cryptoOptions, err := data.options()
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(contents)
+	cryptoOptions = append(cryptoOptions,
+		WithHeader(cryptoUnencryptedLength, strconv.Itoa(len(contents))),
+		WithHeader(cryptoUnencryptedMD5, b64.EncodeToString(sum[:])))
+	return client.api.PutObject(object, encrypted, contentType, append(options, cryptoOptions...)...)
+}
+
+// cipherDataOf unwraps the envelope in header, nil if the object is not encrypted
+func (client *CryptoClient) cipherDataOf(object string, header http.Header) (*cipherData, error) {
+	if header.Get(cryptoKey) == "" {
+		return nil, nil
+	}
+	algorithm := header.Get(cryptoCekAlg)
+	if algorithm != CryptoAESCTR && algorithm != CryptoAESGCM {
+		return nil, fmt.Errorf("oss: %s is encrypted with the unknown algorithm %s", object, algorithm)
+	}
+	matDesc := normalizeMatDesc(header.Get(cryptoMatDesc))
+	master := client.master
+	if matDesc != master.MatDesc() {
+		master = client.keys[matDesc]
+		if master == nil {
+			return nil, fmt.Errorf("oss: no master key for %s, matdesc %s", object, matDesc)
+		}
+	}
+	if wrapAlg := header.Get(cryptoWrapAlg); wrapAlg != master.WrapAlgorithm() {
+		return nil, fmt.Errorf("oss: %s is wrapped with %s, not %s", object, wrapAlg, master.WrapAlgorithm())
+	}
+	data := &cipherData{algorithm: algorithm, master: master}
+	for _, v := range []struct {
+		header string
+		value  *[]byte
+	}{{cryptoKey, &data.key}, {cryptoStart, &data.iv}} {
+		wrapped, err := b64.DecodeString(header.Get(v.header))
+		if err != nil {
+			return nil, fmt.Errorf("oss: invalid envelope of %s: %v", object, err)
+		}
+		if *v.value, err = master.Unwrap(wrapped); err != nil {
+			return nil, fmt.Errorf("oss: cant unwrap the data key of %s: %v", object, err)
+		}
+	}
+	if len(data.iv) != cryptoBlockSize {
+		return nil, fmt.Errorf("oss: invalid IV of %s", object)
+	}
+	return data, nil
+}
+
+// normalizeMatDesc lets the keys match the json of the other sdks regardless of its format
+func normalizeMatDesc(matDesc string) string {
+	var desc map[string]string
+	if json.Unmarshal([]byte(matDesc), &desc) != nil {
+		return matDesc
+	}
+	normalized, _ := encodeMatDesc(desc)
+	return normalized
+}
+
+func (client *CryptoClient) GetObject(object string, options ...Option) ([]byte, error) {
+	r, _, err := client.GetObjectRange(object, -1, -1, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// GetObjectRange decrypts the plain text from start to end, the unencrypted objects
+// are returned as they are. The range is widened to the blocks of the cipher for the GET.
+func (client *CryptoClient) GetObjectRange(object string, start, end int64, options ...Option) (*ReaderWithBytes, int, error) {
+	ranged := start >= 0 || end >= 0
+	if start < 0 {
+		start = 0
+	}
+	aligned := start - start%cryptoBlockSize
+	if !ranged {
+		aligned = -1
+	}
+	hresp, err := client.api.getObject(object, aligned, end, options)
+	if err != nil {
+		return nil, -1, err
+	}
+	data, err := client.cipherDataOf(object, hresp.Header)
+	if err != nil {
+		hresp.Body.Close()
+		return nil, -1, err
+	}
+	// oss answers the invalid ranges with the whole object
+	if hresp.StatusCode != http.StatusPartialContent {
+		start, aligned = 0, 0
+	}
+
+	var body io.ReadCloser
+	switch {
+	case data == nil:
+		body, err = discard(hresp.Body, start-aligned)
+	case data.algorithm == CryptoAESGCM:
+		body, err = client.openGCM(object, data, hresp.Body, ranged)
+	default:
+		var stream cipher.Stream
+		if stream, err = data.stream(aligned); err != nil {
+			hresp.Body.Close()
+			return nil, -1, err
+		}
+		decrypted := &cryptoReadCloser{cipher.StreamReader{S: stream, R: hresp.Body}, hresp.Body}
+		body, err = discard(decrypted, start-aligned)
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+	return &ReaderWithBytes{ReadCloser: body}, hresp.StatusCode, nil
+}
+
+// openGCM authenticates the whole object before returning its plain text
+func (client *CryptoClient) openGCM(object string, data *cipherData, r io.ReadCloser, ranged bool) (io.ReadCloser, error) {
+	defer r.Close()
+	if ranged {
+		return nil, fmt.Errorf(cryptoUnsupported, "range GET", CryptoAESGCM)
+	}
+	encrypted, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := data.gcm()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, data.iv, encrypted, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oss: cant decrypt %s: %v", object, err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(plain)), nil
+}
+
+type cryptoReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// discard skips the first n bytes of r
+func discard(r io.ReadCloser, n int64) (io.ReadCloser, error) {
+	if n <= 0 {
+		return r, nil
+	}
+	if _, err := io.CopyN(ioutil.Discard, r, n); err != nil && err != io.EOF {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// CryptoUploadContext is an encrypted multipart upload, every part but the last one
+// must be PartSize bytes, which is a multiple of 16. The data key only lives in memory,
+// the upload can't be resumed by another CryptoClient.
+type CryptoUploadContext struct {
+	*UploadContext
+	PartSize int64
+	DataSize int64 // the size of the whole object, 0 if unknown
+	data     *cipherData
+}
+
+func (client *CryptoClient) InitMultipartUpload(object, contentType string, partSize, dataSize int64, options ...Option) (*CryptoUploadContext, error) {
+	if client.algorithm != CryptoAESCTR {
+		return nil, fmt.Errorf(cryptoUnsupported, "multipart upload", client.algorithm)
+	}
+	if partSize <= 0 || partSize%cryptoBlockSize != 0 {
+		return nil, fmt.Errorf("oss: the part size %d is not a multiple of %d", partSize, cryptoBlockSize)
+	}
+	data, err := newCipherData(client.algorithm, client.master)
+	if err != nil {
+		return nil, err
+	}
+	cryptoOptions, err := data.options()
+	if err != nil {
+		return nil, err
+	}
+	cryptoOptions = append(cryptoOptions, WithHeader(cryptoPartSize, strconv.FormatInt(partSize, 10)))
+	if dataSize > 0 {
+		cryptoOptions = append(cryptoOptions, WithHeader(cryptoDataSize, strconv.FormatInt(dataSize, 10)))
+	}
+	context, err := client.api.InitMultipartUpload(object, contentType, append(options, cryptoOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &CryptoUploadContext{context, partSize, dataSize, data}, nil
+}
+
+func (client *CryptoClient) UploadMultipart(context *CryptoUploadContext, contents []byte, partNumber int, options ...Option) error {
+	if partNumber < 1 {
+		return fmt.Errorf("oss: invalid part number %d", partNumber)
+	}
+	size := int64(len(contents))
+	if size > context.PartSize {
+		return fmt.Errorf("oss: the part %d is larger than the part size %d", partNumber, context.PartSize)
+	}
+	// a short part is the last one, the parts after it would be encrypted at the wrong offset
+	for _, part := range context.Parts {
+		if part.PartNumber > partNumber && size < context.PartSize ||
+			part.PartNumber < partNumber && part.Size < context.PartSize {
+			return fmt.Errorf("oss: only the last part can be smaller than the part size %d", context.PartSize)
+		}
+	}
+	if context.DataSize > 0 && int64(partNumber-1)*context.PartSize+size > context.DataSize {
+		return fmt.Errorf("oss: the part %d exceeds the data size %d", partNumber, context.DataSize)
+	}
+	encrypted, err := context.data.encrypt(contents, int64(partNumber-1)*context.PartSize)
+	if err != nil {
+		return err
+	}
+	return client.api.UploadMultipart(context.UploadContext, encrypted, partNumber, options...)
+}
+
+// CompleteMultipart checks the parts are numbered from 1 without gap, are PartSize bytes
+// but the last one, and add up to the DataSize if known
+func (client *CryptoClient) CompleteMultipart(context *CryptoUploadContext, options ...Option) error {
+	parts := append(partSlice(nil), context.Parts...)
+	sort.Sort(parts)
+	size := int64(0)
+	for i, part := range parts {
+		if part.PartNumber != i+1 {
+			return fmt.Errorf("oss: the part %d is missing", i+1)
+		}
+		if i < len(parts)-1 && part.Size != context.PartSize {
+			return fmt.Errorf("oss: the part %d is not the part size %d", part.PartNumber, context.PartSize)
+		}
+		size += part.Size
+	}
+	if context.DataSize > 0 && size != context.DataSize {
+		return fmt.Errorf("oss: the parts are %d bytes instead of the data size %d", size, context.DataSize)
+	}
+	return client.api.CompleteMultipart(context.UploadContext, options...)
+}
+
+func (client *CryptoClient) AbortMultipart(context *CryptoUploadContext) error {
+	return client.api.AbortMultipart(context.UploadContext)
+}
@@ -0,0 +1,217 @@
+package oss
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/topikachu/oss-mini-go-sdk/oss/ossfake"
+)
+
+func newCryptoTestClient(t *testing.T, algorithm string, master MasterKey, keys ...MasterKey) (*CryptoClient, *ossfake.Server) {
+	api, server := newFakeClient(t)
+	client, err := NewCryptoClient(api, algorithm, master, keys...)
+	if err != nil {
+		t.Fatalf("cant create crypto client %v", err)
+	}
+	return client, server
+}
+
+func TestAESKeyWrap(t *testing.T) {
+	// RFC 3394 4.1
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF")
+	master, _ := NewAESMasterKey(kek, nil)
+	wrapped, err := master.Wrap(key)
+	if err != nil {
+		t.Fatalf("cant wrap %v", err)
+	}
+	if expected := "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5"; hex.EncodeToString(wrapped) != expected {
+		t.Errorf("wrong wrapped key expected %s, actual %x", expected, wrapped)
+	}
+	unwrapped, err := master.Unwrap(wrapped)
+	if err != nil || !bytes.Equal(unwrapped, key) {
+		t.Errorf("cant unwrap %x %v", unwrapped, err)
+	}
+	wrapped[0] ^= 1
+	if _, err := master.Unwrap(wrapped); err == nil {
+		t.Errorf("the corrupted key should not be unwrapped")
+	}
+}
+
+func TestCryptoClientInteroperability(t *testing.T) {
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	master, _ := NewRSAMasterKey(nil, privateKey, map[string]string{"key": "rsa"})
+	client, server := newCryptoTestClient(t, CryptoAESCTR, master)
+	contents := []byte(strings.Repeat("hello encrypted world ", 100))
+	if err := client.PutObject("a.txt", contents, "text/plain"); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	encrypted, header, _ := server.Object("a.txt")
+	if bytes.Contains(encrypted, []byte("hello")) {
+		t.Fatalf("the object is not encrypted")
+	}
+
+	// decrypt it the way the official sdks do
+	if header.Get(cryptoCekAlg) != "AES/CTR/NoPadding" || header.Get(cryptoWrapAlg) != "RSA/NONE/PKCS1Padding" ||
+		header.Get(cryptoMatDesc) != `{"key":"rsa"}` || header.Get(cryptoUnencryptedLength) != strconv.Itoa(len(contents)) {
+		t.Errorf("wrong envelope %v", header)
+	}
+	unwrap := func(name string) []byte {
+		wrapped, _ := b64.DecodeString(header.Get(name))
+		plain, err := rsa.DecryptPKCS1v15(rand.Reader, privateKey, wrapped)
+		if err != nil {
+			t.Fatalf("cant unwrap %s %v", name, err)
+		}
+		return plain
+	}
+	block, _ := aes.NewCipher(unwrap(cryptoKey))
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCTR(block, unwrap(cryptoStart)).XORKeyStream(decrypted, encrypted)
+	if !bytes.Equal(decrypted, contents) {
+		t.Errorf("the official sdks cant decrypt the object")
+	}
+
+	data, err := client.GetObject("a.txt")
+	if err != nil || !bytes.Equal(data, contents) {
+		t.Errorf("cant get object %v", err)
+	}
+	for _, r := range [][2]int64{{0, 9}, {5, 40}, {16, 31}, {17, -1}, {2190, 5000}} {
+		reader, _, err := client.GetObjectRange("a.txt", r[0], r[1])
+		if err != nil {
+			t.Fatalf("cant get range %v %v", r, err)
+		}
+		end := r[1]
+		if end < 0 || end >= int64(len(contents)) {
+			end = int64(len(contents)) - 1
+		}
+		if expected := contents[r[0] : end+1]; !bytes.Equal(reader.Bytes(), expected) {
+			t.Errorf("wrong range %v expected %q, actual %q", r, expected, reader.Bytes())
+		}
+	}
+}
+
+func TestCryptoClientGCM(t *testing.T) {
+	master, _ := NewAESMasterKey(bytes.Repeat([]byte{1}, 32), map[string]string{"key": "aes"})
+	client, server := newCryptoTestClient(t, CryptoAESGCM, master)
+	if err := client.PutObject("a.txt", []byte("hello"), "text/plain"); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	if data, err := client.GetObject("a.txt"); err != nil || string(data) != "hello" {
+		t.Errorf("cant get object %s %v", data, err)
+	}
+	if _, _, err := client.GetObjectRange("a.txt", 1, 2); err == nil {
+		t.Errorf("the range GET should fail with gcm")
+	}
+	if _, err := client.InitMultipartUpload("b.txt", "text/plain", 1024, 0); err == nil {
+		t.Errorf("the multipart upload should fail with gcm")
+	}
+	encrypted, header, _ := server.Object("a.txt")
+	encrypted[0] ^= 1
+	server.SetObject("a.txt", encrypted, header)
+	if _, err := client.GetObject("a.txt"); err == nil {
+		t.Errorf("the tampered object should not be decrypted")
+	}
+}
+
+func TestCryptoClientMultipart(t *testing.T) {
+	master, _ := NewAESMasterKey(bytes.Repeat([]byte{1}, 16), nil)
+	client, server := newCryptoTestClient(t, CryptoAESCTR, master)
+	const partSize = 100 * 1024
+	contents := []byte(strings.Repeat("0123456789", 25000))
+	context, err := client.InitMultipartUpload("a.txt", "text/plain", partSize, int64(len(contents)))
+	if err != nil {
+		t.Fatalf("cant init upload %v", err)
+	}
+	if _, err := client.InitMultipartUpload("b.txt", "text/plain", 100, 0); err == nil {
+		t.Errorf("the unaligned part size should fail")
+	}
+	for partNumber, start := 3, int64(2*partSize); start >= 0; partNumber, start = partNumber-1, start-partSize {
+		end := start + partSize
+		if end > int64(len(contents)) {
+			end = int64(len(contents))
+		}
+		if err := client.UploadMultipart(context, contents[start:end], partNumber); err != nil {
+			t.Fatalf("cant upload part %v", err)
+		}
+	}
+	if err := client.CompleteMultipart(context); err != nil {
+		t.Fatalf("cant complete upload %v", err)
+	}
+	if _, header, _ := server.Object("a.txt"); header.Get(cryptoPartSize) != "102400" || header.Get(cryptoDataSize) != "250000" {
+		t.Errorf("wrong envelope %v", header)
+	}
+	if data, err := client.GetObject("a.txt"); err != nil || !bytes.Equal(data, contents) {
+		t.Errorf("cant get object %v", err)
+	}
+	reader, _, err := client.GetObjectRange("a.txt", partSize-10, partSize+10)
+	if err != nil || !bytes.Equal(reader.Bytes(), contents[partSize-10:partSize+11]) {
+		t.Errorf("wrong range across the parts %q %v", reader.Bytes(), err)
+	}
+}
+
+func TestCryptoClientMultipartShortPart(t *testing.T) {
+	master, _ := NewAESMasterKey(bytes.Repeat([]byte{1}, 16), nil)
+	client, _ := newCryptoTestClient(t, CryptoAESCTR, master)
+	const partSize = 100 * 1024
+	part := bytes.Repeat([]byte("a"), partSize)
+
+	context, _ := client.InitMultipartUpload("a.txt", "text/plain", partSize, 0)
+	if err := client.UploadMultipart(context, part[:100], 1); err != nil {
+		t.Fatalf("cant upload part %v", err)
+	}
+	if err := client.UploadMultipart(context, part, 2); err == nil {
+		t.Errorf("the part after a short part should fail")
+	}
+
+	context, _ = client.InitMultipartUpload("b.txt", "text/plain", partSize, 0)
+	if err := client.UploadMultipart(context, part, 2); err != nil {
+		t.Fatalf("cant upload part %v", err)
+	}
+	if err := client.UploadMultipart(context, part[:100], 1); err == nil {
+		t.Errorf("the short part before another part should fail")
+	}
+	if err := client.CompleteMultipart(context); err == nil {
+		t.Errorf("the upload missing the part 1 should not complete")
+	}
+
+	context, _ = client.InitMultipartUpload("c.txt", "text/plain", partSize, 2*partSize)
+	if err := client.UploadMultipart(context, part, 1); err != nil {
+		t.Fatalf("cant upload part %v", err)
+	}
+	if err := client.CompleteMultipart(context); err == nil {
+		t.Errorf("the upload shorter than the data size should not complete")
+	}
+}
+
+func TestCryptoClientKeys(t *testing.T) {
+	oldKey, _ := NewAESMasterKey(bytes.Repeat([]byte{1}, 32), map[string]string{"key": "old"})
+	newKey, _ := NewAESMasterKey(bytes.Repeat([]byte{2}, 32), map[string]string{"key": "new"})
+	oldClient, server := newCryptoTestClient(t, CryptoAESCTR, oldKey)
+	if err := oldClient.PutObject("a.txt", []byte("hello"), "text/plain"); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	server.SetObject("plain.txt", []byte("plain"), nil)
+
+	newClient, err := NewCryptoClient(oldClient.api, CryptoAESCTR, newKey)
+	if err != nil {
+		t.Fatalf("cant create crypto client %v", err)
+	}
+	if _, err := newClient.GetObject("a.txt"); err == nil {
+		t.Errorf("the object wrapped by another key should fail")
+	}
+	newClient, _ = NewCryptoClient(oldClient.api, CryptoAESCTR, newKey, oldKey)
+	if data, err := newClient.GetObject("a.txt"); err != nil || string(data) != "hello" {
+		t.Errorf("cant get object with the old key %s %v", data, err)
+	}
+	reader, _, err := newClient.GetObjectRange("plain.txt", 2, -1)
+	if err != nil || string(reader.Bytes()) != "ain" {
+		t.Errorf("the unencrypted objects should be returned as they are %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package oss
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// Error represents an error in an operation with OSS.
+type Error struct {
+	StatusCode int    // HTTP status code (200, 403, ...)
+	Code       string // Oss error code ("UnsupportedOperation", ...)
+	Message    string // The human-oriented error message
+	BucketName string
+	RequestId  string
+	HostId     string
+	EC         string // The error detail code, look it up in the oss error center
+
+	Operation string      `xml:"-"` // The oss api name ("GetObject", ...)
+	Key       string      `xml:"-"` // The object key, empty for the bucket operations
+	Header    http.Header `xml:"-"` // The response headers
+	Body      []byte      `xml:"-"` // The raw response body if it's not an oss error document
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("oss: ")
+	if e.Operation != "" {
+		b.WriteString(e.Operation + " ")
+	}
+	if e.Key != "" {
+		b.WriteString(e.Key + " ")
+	}
+	fmt.Fprintf(&b, "%d", e.StatusCode)
+	if e.Code != "" {
+		b.WriteString(" " + e.Code)
+	}
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	if e.RequestId != "" {
+		b.WriteString(", request id " + e.RequestId)
+	}
+	if e.EC != "" {
+		b.WriteString(", ec " + e.EC)
+	}
+	return b.String()
+}
+
+// the targets of errors.Is
+var (
+	ErrNotFound           = errors.New("oss: not found")
+	ErrAccessDenied       = errors.New("oss: access denied")
+	ErrPreconditionFailed = errors.New("oss: precondition failed")
+	ErrNotModified        = errors.New("oss: not modified")
+	ErrAlreadyExists      = errors.New("oss: already exists")
+	ErrNotRestored        = errors.New("oss: archived object not restored")
+	ErrRestoreInProgress  = errors.New("oss: restore in progress")
+)
+
+// Is lets errors.Is(err, ErrNotFound) match the oss errors by status code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrAccessDenied:
+		return e.StatusCode == http.StatusForbidden
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrNotModified:
+		return e.StatusCode == http.StatusNotModified
+	case ErrAlreadyExists:
+		return e.Code == "FileAlreadyExists"
+	case ErrNotRestored:
+		return e.Code == "InvalidObjectState"
+	case ErrRestoreInProgress:
+		return e.Code == "RestoreAlreadyInProgress"
+	}
+	return false
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+func IsAccessDenied(err error) bool {
+	return errors.Is(err, ErrAccessDenied)
+}
+
+func IsPreconditionFailed(err error) bool {
+	return errors.Is(err, ErrPreconditionFailed)
+}
+
+// IsNotModified reports the conditional reads whose object is not modified
+func IsNotModified(err error) bool {
+	return errors.Is(err, ErrNotModified)
+}
+
+// IsAlreadyExists reports the writes with ForbidOverwrite of an existing object
+func IsAlreadyExists(err error) bool {
+	return errors.Is(err, ErrAlreadyExists)
+}
+
+// IsNotRestored reports the reads of an Archive or ColdArchive object
+// which is not restored by RestoreObject
+func IsNotRestored(err error) bool {
+	return errors.Is(err, ErrNotRestored)
+}
+
+func IsRestoreInProgress(err error) bool {
+	return errors.Is(err, ErrRestoreInProgress)
+}
+
+// IsRetryable reports the server side, network and integrity failures
+// which may succeed if the request is sent again
+func IsRetryable(err error) bool {
+	var ossErr *Error
+	if errors.As(err, &ossErr) {
+		switch ossErr.Code {
+		case "RequestTimeTooSkewed", "InternalError", "ServiceUnavailable", "RequestTimeout":
+			return true
+		}
+		return ossErr.StatusCode >= 500 || ossErr.StatusCode == http.StatusTooManyRequests
+	}
+	if IsIntegrityError(err) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IntegrityError reports a transfer whose CRC64 doesn't match the one computed by oss
+type IntegrityError struct {
+	Operation string
+	Key       string
+	ClientCRC uint64
+	ServerCRC string // the x-oss-hash-crc64ecma header
+	RequestId string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("oss: %s %s crc64 mismatch, client %d, server %s, request id %s",
+		e.Operation, e.Key, e.ClientCRC, e.ServerCRC, e.RequestId)
+}
+
+func IsIntegrityError(err error) bool {
+	var integrityErr *IntegrityError
+	return errors.As(err, &integrityErr)
+}
+
+// buildError reads the error document of r, the HEAD responses have no body
+// but may carry the base64 encoded document in the x-oss-err header
+func buildError(req *request, r *http.Response) error {
+	defer r.Body.Close()
+	err := Error{}
+	body, readErr := ioutil.ReadAll(r.Body)
+	if len(body) == 0 {
+		if header := r.Header.Get("x-oss-err"); header != "" {
+			body, _ = b64.DecodeString(header)
+		}
+	}
+	if len(body) > 0 {
+		var doc struct {
+			XMLName xml.Name `xml:"Error"`
+			Error
+		}
+		if readErr == nil && xml.Unmarshal(body, &doc) == nil {
+			err = doc.Error
+		} else {
+			err.Body = body
+		}
+	}
+
+	err.StatusCode = r.StatusCode
+	err.Operation = req.operation
+	if err.Operation == "" {
+		err.Operation = req.method
+	}
+	err.Key = req.object
+	err.Header = r.Header
+	if err.RequestId == "" {
+		err.RequestId = r.Header.Get("x-oss-request-id")
+	}
+	if err.EC == "" {
+		err.EC = r.Header.Get("x-oss-ec")
+	}
+	if err.Message == "" {
+		err.Message = http.StatusText(r.StatusCode)
+	}
+
+	return &err
+}
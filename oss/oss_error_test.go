@@ -0,0 +1,97 @@
+package oss
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newErrorResponse(statusCode int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestBuildError(t *testing.T) {
+	req := &request{operation: "GetObject", method: "GET", object: "dir/a.txt"}
+	err := buildError(req, newErrorResponse(404, nil, `<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>NoSuchKey</Code>
+  <Message>The specified key does not exist.</Message>
+  <RequestId>5C3D9175B6FC201293AD****</RequestId>
+  <HostId>bucket.oss-cn-hangzhou.aliyuncs.com</HostId>
+  <Key>dir/a.txt</Key>
+  <EC>0026-00000001</EC>
+</Error>`))
+	expected := "oss: GetObject dir/a.txt 404 NoSuchKey: The specified key does not exist., request id 5C3D9175B6FC201293AD****, ec 0026-00000001"
+	if err.Error() != expected {
+		t.Errorf("wrong error string expected %s, actual %s", expected, err.Error())
+	}
+	if !IsNotFound(err) || IsAccessDenied(err) || IsRetryable(err) {
+		t.Errorf("wrong error classification %v", err)
+	}
+
+	var ossErr *Error
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &ossErr) || ossErr.Code != "NoSuchKey" {
+		t.Errorf("errors.As should find the oss error")
+	}
+	if !errors.Is(fmt.Errorf("wrapped: %w", err), ErrNotFound) {
+		t.Errorf("errors.Is should match ErrNotFound")
+	}
+}
+
+func TestBuildErrorWithoutBody(t *testing.T) {
+	req := &request{operation: "HeadObject", method: "HEAD", object: "a.txt"}
+	header := http.Header{
+		"X-Oss-Request-Id": {"5C3D8D2A0ACA54D87B43****"},
+		"X-Oss-Ec":         {"0026-00000001"},
+	}
+	err := buildError(req, newErrorResponse(404, header, "")).(*Error)
+	if err.Error() != "oss: HeadObject a.txt 404: Not Found, request id 5C3D8D2A0ACA54D87B43****, ec 0026-00000001" {
+		t.Errorf("wrong error string %s", err.Error())
+	}
+
+	header.Set("x-oss-err", b64.EncodeToString([]byte("<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")))
+	err = buildError(req, newErrorResponse(404, header, "")).(*Error)
+	if err.Code != "NoSuchKey" || err.Message != "The specified key does not exist." {
+		t.Errorf("the x-oss-err header should be decoded %#v", err)
+	}
+
+	err = buildError(&request{method: "GET"}, newErrorResponse(502, nil, "<html>Bad Gateway</html>")).(*Error)
+	if string(err.Body) != "<html>Bad Gateway</html>" || err.Code != "" || err.Operation != "GET" {
+		t.Errorf("non xml body should be kept %#v", err)
+	}
+	if !IsRetryable(err) {
+		t.Errorf("502 should be retryable")
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	cases := []struct {
+		err                                           error
+		notFound, accessDenied, precondition, retried bool
+	}{
+		{&Error{StatusCode: 403, Code: "AccessDenied"}, false, true, false, false},
+		{&Error{StatusCode: 412, Code: "PreconditionFailed"}, false, false, true, false},
+		{&Error{StatusCode: 403, Code: "RequestTimeTooSkewed"}, false, true, false, true},
+		{&Error{StatusCode: 503, Code: "ServiceUnavailable"}, false, false, false, true},
+		{io.ErrUnexpectedEOF, false, false, false, true},
+		{errors.New("other"), false, false, false, false},
+	}
+	for _, c := range cases {
+		if IsNotFound(c.err) != c.notFound || IsAccessDenied(c.err) != c.accessDenied ||
+			IsPreconditionFailed(c.err) != c.precondition || IsRetryable(c.err) != c.retried {
+			t.Errorf("wrong classification of %v", c.err)
+		}
+	}
+}
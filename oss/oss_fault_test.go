@@ -0,0 +1,97 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/topikachu/oss-mini-go-sdk/oss/ossfake"
+)
+
+func newFakeClient(t *testing.T) (*OssApi, *ossfake.Server) {
+	server := ossfake.NewServer("bucket", "ak", "sk")
+	t.Cleanup(server.Close)
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.SetEndpoint(server.URL)
+	return client, server
+}
+
+func TestFaultErrorCode(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.Inject(ossfake.Fault{Method: "PUT", Times: 1, Status: http.StatusServiceUnavailable, Code: "ServiceUnavailable"})
+	err := client.PutObject("a.txt", []byte("hello"), "text/plain")
+	var ossErr *Error
+	if !errors.As(err, &ossErr) || ossErr.Code != "ServiceUnavailable" || !IsRetryable(err) {
+		t.Fatalf("expected a retryable error %v", err)
+	}
+	if err := client.PutObject("a.txt", []byte("hello"), "text/plain"); err != nil {
+		t.Errorf("the retry should succeed %v", err)
+	}
+}
+
+func TestFaultDroppedConnection(t *testing.T) {
+	client, server := newFakeClient(t)
+	client.PutObject("a.txt", []byte("hello world"), "text/plain")
+	server.Inject(ossfake.Fault{Method: "GET", Drop: true, DropAfter: 5})
+	if _, err := client.GetObject("a.txt"); err == nil || !IsRetryable(err) {
+		t.Errorf("expected a retryable error %v", err)
+	}
+	server.ClearFaults()
+	server.Inject(ossfake.Fault{Method: "GET", Drop: true, DropAfter: -1})
+	if _, err := client.GetObject("a.txt"); err == nil {
+		t.Errorf("expected the request to fail")
+	}
+}
+
+func TestFaultCorruption(t *testing.T) {
+	client, server := newFakeClient(t)
+	client.PutObject("a.txt", []byte("hello world"), "text/plain")
+	server.Inject(ossfake.Fault{Method: "GET", Times: 1, Corrupt: true})
+	if _, err := client.GetObject("a.txt"); !IsIntegrityError(err) {
+		t.Errorf("expected an integrity error %v", err)
+	}
+	server.Inject(ossfake.Fault{Method: "PUT", Times: 1, Corrupt: true})
+	err := client.PutObject("b.txt", []byte("hello world"), "text/plain")
+	var ossErr *Error
+	if !errors.As(err, &ossErr) || ossErr.Code != "BadDigest" {
+		t.Errorf("expected a bad digest error %v", err)
+	}
+}
+
+func TestFaultDelay(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.Inject(ossfake.Fault{Delay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetObjectMetadata("a.txt", WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to time out %v", err)
+	}
+}
+
+func TestFaultClockSkew(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.SetClockSkew(-time.Hour)
+	err := client.PutObject("a.txt", []byte("hello"), "text/plain")
+	var ossErr *Error
+	if !errors.As(err, &ossErr) || ossErr.Code != "RequestTimeTooSkewed" || !IsRetryable(err) {
+		t.Errorf("expected the request time too skewed %v", err)
+	}
+}
+
+func TestFaultCorruptionReadAll(t *testing.T) {
+	client, server := newFakeClient(t)
+	client.PutObject("a.txt", []byte("hello world"), "text/plain")
+	server.Inject(ossfake.Fault{Method: "GET", Corrupt: true})
+	r, _, err := client.GetObjectRange("a.txt", -1, -1)
+	if err != nil {
+		t.Fatalf("cant get object %v", err)
+	}
+	if _, err := r.ReadAll(); !IsIntegrityError(err) {
+		t.Errorf("expected an integrity error %v", err)
+	}
+	if data := r.Bytes(); data != nil {
+		t.Errorf("the corrupted bytes should not be returned %q", data)
+	}
+}
@@ -6,13 +6,13 @@ import (
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
-	log "github.com/Sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -35,20 +35,43 @@ func (api *OssApi) prepare(req *request) error {
 	}
 
 	req.baseurl = api.baseUrl()
-	log.Debugf("baseurl is %s", req.baseurl)
 	u, err := url.Parse(req.baseurl)
 	if err != nil {
 		return fmt.Errorf("bad oss endpoint URL %q: %v", req.baseurl, err)
 	}
 	req.headers["Host"] = []string{u.Host}
-	req.headers["Date"] = []string{time.Now().In(time.UTC).Format(http.TimeFormat)}
+	now := time.Now().In(time.UTC)
+	req.headers["Date"] = []string{now.Format(http.TimeFormat)}
 	//req.headers["Date"] = []string{"Thu, 25 Jun 2015 06:29:40 GMT"}
 
-	api.sign(req)
+	creds, err := api.credentials.get()
+	if err != nil {
+		return err
+	}
+	if creds.SecurityToken != "" {
+		req.headers["x-oss-security-token"] = []string{creds.SecurityToken}
+	}
+	if err := api.runPreSignHooks(req); err != nil {
+		return err
+	}
+	if api.signatureVersion == SignatureV4 {
+		api.signV4(req, creds, now)
+	} else {
+		api.sign(req, creds)
+	}
 	return nil
 }
 
+// SetEndpoint replaces the url of the bucket, e.g. "https://cdn.example.com" for
+// a custom domain bound to the bucket or the URL of an ossfake.Server
+func (api *OssApi) SetEndpoint(endpoint string) {
+	api.endpoint = strings.TrimSuffix(endpoint, "/")
+}
+
 func (api *OssApi) baseUrl() string {
+	if api.endpoint != "" {
+		return api.endpoint
+	}
 	var protocol = "http"
 	if api.secure {
 		protocol = "https"
@@ -102,70 +125,82 @@ func (api *OssApi) run(req *request) (*http.Response, error) {
 		return nil, err
 	}
 
-	hreq := &http.Request{
+	hreq := (&http.Request{
 		URL:        u,
 		Method:     req.method,
 		ProtoMajor: 1,
 		ProtoMinor: 1,
 		Close:      true,
 		Header:     req.headers,
-	}
+	}).WithContext(req.context())
 
 	if v, ok := req.headers["Content-Length"]; ok {
 		hreq.ContentLength, _ = strconv.ParseInt(v[0], 10, 64)
 		delete(req.headers, "Content-Length")
 	}
 
+	tracker := newProgressTracker(req)
 	if req.payload != nil {
-		hreq.Body = ioutil.NopCloser(bytes.NewReader(req.payload))
+		body := newLimitedReader(bytes.NewReader(req.payload), api.uploadLimiter, req.limiter)
+		if tracker != nil {
+			tracker.started(int64(len(req.payload)))
+			body = &progressReader{Reader: body, tracker: tracker}
+		}
+		hreq.Body = ioutil.NopCloser(body)
 	}
-	if log.GetLevel() == log.DebugLevel {
-		dump, _ := httputil.DumpRequestOut(hreq, false)
-		log.Debugf("request } -> %s\n", dump)
 
-	}
-
-	hresp, err := http.DefaultClient.Do(hreq)
+	hresp, err := api.handler()(hreq)
 	if err != nil {
+		tracker.failed(err)
 		return nil, err
 	}
-	if log.GetLevel() == log.DebugLevel {
-		dump, _ := httputil.DumpResponse(hresp, false)
-		log.Debugf("response } -> %s\n", dump)
-
-	}
 
 	if hresp.StatusCode < 200 || hresp.StatusCode >= 300 {
-		return nil, buildError(hresp)
+		err := buildError(req, hresp)
+		api.logger.Debug("oss error", "error", err)
+		tracker.failed(err)
+		return nil, err
+	}
+	if req.payload == nil {
+		if body := newLimitedReader(hresp.Body, api.downloadLimiter, req.limiter); body != hresp.Body {
+			hresp.Body = &limitedReadCloser{body, hresp.Body}
+		}
+	}
+	if tracker != nil {
+		if req.payload != nil {
+			tracker.completed()
+		} else {
+			// the download completes when the caller reads the body to the end
+			tracker.started(hresp.ContentLength)
+			hresp.Body = &progressReadCloser{progressReader{hresp.Body, tracker, true}, hresp.Body}
+		}
 	}
 	return hresp, err
 }
 
-// Error represents an error in an operation with OSS.
-type Error struct {
-	StatusCode int    // HTTP status code (200, 403, ...)
-	Code       string // Oss error code ("UnsupportedOperation", ...)
-	Message    string // The human-oriented error message
-	BucketName string
-	RequestId  string
-	HostId     string
+// SetTransport sends the requests with transport, e.g. a proxy or an ossrecord.Recorder,
+// nil restores the http.DefaultClient
+func (api *OssApi) SetTransport(transport http.RoundTripper) {
+	api.transport = transport
 }
 
-func (e *Error) Error() string {
-	return e.Message
-}
-func buildError(r *http.Response) error {
-
-	err := Error{}
-	// TODO return error if Unmarshal fails?
-	xml.NewDecoder(r.Body).Decode(&err)
-	defer r.Body.Close()
-	err.StatusCode = r.StatusCode
-	if err.Message == "" {
-		err.Message = r.Status
+// send is the innermost handler of the middleware chain
+func (api *OssApi) send(hreq *http.Request) (*http.Response, error) {
+	if api.logger.Enabled() {
+		dump, _ := httputil.DumpRequestOut(redactRequest(hreq), false)
+		api.logger.Debug("oss request", "dump", string(dump))
 	}
-
-	log.Debugf("err: %#v\n", err)
-
-	return &err
+	client := http.DefaultClient
+	if api.transport != nil {
+		client = &http.Client{Transport: api.transport}
+	}
+	hresp, err := client.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	if api.logger.Enabled() {
+		dump, _ := httputil.DumpResponse(redactResponse(hresp), false)
+		api.logger.Debug("oss response", "dump", string(dump))
+	}
+	return hresp, nil
 }
@@ -0,0 +1,133 @@
+package oss
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Logger receives the debug output of the client,
+// the secrets are redacted before they are logged
+type Logger interface {
+	// Enabled reports whether the debug output is wanted,
+	// the request and response dumps are skipped otherwise
+	Enabled() bool
+	Debug(msg string, keysAndValues ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Enabled() bool                         { return false }
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger logs at the slog.LevelDebug level of logger
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return &slogLogger{logger}
+}
+
+func (l *slogLogger) Enabled() bool {
+	return l.logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+func (l *slogLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.Debug(msg, keysAndValues...)
+}
+
+// SetLogger sets the debug logger of the client, nil disables the logging
+func (api *OssApi) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	api.logger = logger
+}
+
+const redacted = "REDACTED"
+
+var secretHeaders = map[string]bool{
+	"authorization":                                         true,
+	"x-oss-security-token":                                  true,
+	"x-oss-server-side-encryption-customer-key":             true,
+	"x-oss-copy-source-server-side-encryption-customer-key": true,
+}
+
+var secretParams = map[string]bool{
+	"Signature":            true,
+	"security-token":       true,
+	"x-oss-signature":      true,
+	"x-oss-security-token": true,
+}
+
+// the headers whose url may be presigned, e.g. the Location of a redirect
+var urlHeaders = map[string]bool{
+	"location":         true,
+	"content-location": true,
+}
+
+func redactHeader(header http.Header) http.Header {
+	redactedHeader := make(http.Header, len(header))
+	for k, v := range header {
+		if secretHeaders[strings.ToLower(k)] {
+			v = []string{redacted}
+		} else if urlHeaders[strings.ToLower(k)] {
+			urls := make([]string, len(v))
+			for i, vi := range v {
+				urls[i] = vi
+				if u, err := url.Parse(vi); err == nil {
+					urls[i] = redactURL(u).String()
+				}
+			}
+			v = urls
+		}
+		redactedHeader[k] = v
+	}
+	return redactedHeader
+}
+
+func redactURL(u *url.URL) *url.URL {
+	query := u.Query()
+	for k := range query {
+		if secretParams[k] {
+			query[k] = []string{redacted}
+		}
+	}
+	redactedURL := *u
+	redactedURL.RawQuery = query.Encode()
+	return &redactedURL
+}
+
+func redactRequest(hreq *http.Request) *http.Request {
+	redactedRequest := *hreq
+	redactedRequest.Header = redactHeader(hreq.Header)
+	redactedRequest.URL = redactURL(hreq.URL)
+	return &redactedRequest
+}
+
+func redactResponse(hresp *http.Response) *http.Response {
+	redactedResponse := *hresp
+	redactedResponse.Header = redactHeader(hresp.Header)
+	return &redactedResponse
+}
+
+// redactToken hides the security token and the canonical lines of the secret headers
+// in the string to sign
+func redactToken(s string, creds *Credentials) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if kv := strings.SplitN(line, ":", 2); len(kv) == 2 && secretHeaders[kv[0]] {
+			lines[i] = kv[0] + ":" + redacted
+		}
+	}
+	s = strings.Join(lines, "\n")
+	if creds.SecurityToken == "" {
+		return s
+	}
+	// the canonical query of the signature version 4 has the escaped token
+	s = strings.Replace(s, escapeV4(creds.SecurityToken), redacted, -1)
+	return strings.Replace(s, creds.SecurityToken, redacted, -1)
+}
@@ -0,0 +1,108 @@
+package oss
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Enabled() bool { return true }
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.lines = append(l.lines, msg+" "+fmt.Sprint(keysAndValues...))
+}
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-oss-request-id", "request")
+		w.Header().Set("Location", "http://bucket.oss-cn-hangzhou.aliyuncs.com/a.txt?Signature=responsesignature&security-token=secrettoken")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	for _, version := range []SignatureVersion{SignatureV1, SignatureV4} {
+		logger := &recordingLogger{}
+		client := NewWithSecurityToken("oss-cn-hangzhou", "ak", "sk", "secrettoken", "bucket", false)
+		client.SetSignatureVersion(version)
+		client.SetLogger(logger)
+
+		req := &request{method: "HEAD", object: "a.txt"}
+		req.apply([]Option{SSECustomerKey([]byte("customersecretkey")), CopySourceSSECustomerKey([]byte("copysourcesecretkey"))})
+		if err := client.prepare(req); err != nil {
+			t.Fatalf("cant prepare request %v", err)
+		}
+		authorization := req.headers["Authorization"][0]
+		signature := authorization[strings.Index(authorization, ":")+1:]
+		if version == SignatureV4 {
+			signature = authorization[strings.Index(authorization, "Signature=")+len("Signature="):]
+		}
+		req.baseurl = server.URL
+		if _, err := client.run(req); !IsNotFound(err) {
+			t.Errorf("wrong error %v", err)
+		}
+
+		output := strings.Join(logger.lines, "\n")
+		if !strings.Contains(output, "oss request") || !strings.Contains(output, "oss response") || !strings.Contains(output, "oss error") {
+			t.Errorf("missing dumps in the debug output %s", output)
+		}
+		customerKey := base64.StdEncoding.EncodeToString([]byte("customersecretkey"))
+		copySourceKey := base64.StdEncoding.EncodeToString([]byte("copysourcesecretkey"))
+		for _, secret := range []string{"secrettoken", signature, "responsesignature", customerKey, copySourceKey} {
+			if strings.Contains(output, secret) {
+				t.Errorf("the debug output contains the secret %s: %s", secret, output)
+			}
+		}
+	}
+}
+
+func TestLoggerRedactsEscapedToken(t *testing.T) {
+	token := "CAIS/abc+def=="
+	for _, version := range []SignatureVersion{SignatureV1, SignatureV4} {
+		logger := &recordingLogger{}
+		client := NewWithSecurityToken("oss-cn-hangzhou", "ak", "sk", token, "bucket", false)
+		client.SetSignatureVersion(version)
+		client.SetLogger(logger)
+		if _, err := client.PresignUrlFor("GET", "a.txt", time.Minute); err != nil {
+			t.Fatalf("cant presign url %v", err)
+		}
+		output := strings.Join(logger.lines, "\n")
+		if !strings.Contains(output, "oss signature") {
+			t.Errorf("missing signature in the debug output %s", output)
+		}
+		for _, secret := range []string{token, escapeV4(token), url.QueryEscape(token)} {
+			if strings.Contains(output, secret) {
+				t.Errorf("the debug output contains the token %s: %s", secret, output)
+			}
+		}
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	client := NewWithSecurityToken("oss-cn-hangzhou", "ak", "sk", "secrettoken", "bucket", false)
+	presigned := client.GeneratePresignedUrl("a.txt", 60)
+	req, _ := http.NewRequest("GET", presigned, nil)
+	redactedURL := redactURL(req.URL).String()
+	if strings.Contains(redactedURL, "secrettoken") || strings.Contains(redactedURL, req.URL.Query().Get("Signature")) {
+		t.Errorf("the url is not redacted %s", redactedURL)
+	}
+	if !strings.Contains(redactedURL, "OSSAccessKeyId=ak") {
+		t.Errorf("the access key id should be kept %s", redactedURL)
+	}
+}
+
+func TestNopLogger(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.SetLogger(nil)
+	if client.logger.Enabled() {
+		t.Errorf("the default logger should be disabled")
+	}
+}
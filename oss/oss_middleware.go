@@ -0,0 +1,77 @@
+package oss
+
+import (
+	"context"
+	"net/http"
+)
+
+// PreSignHook runs before a request is signed, the headers and query parameters
+// it adds to r are signed with the request
+type PreSignHook func(r *http.Request)
+
+// Handler sends a signed request to oss
+type Handler func(r *http.Request) (*http.Response, error)
+
+// Middleware wraps the sending of the signed requests, e.g. for audit logging,
+// metrics, fault injection or caching. It may add unsigned headers, but any
+// change to the signed headers or the url breaks the signature.
+// The error responses pass through the middlewares before they become an *Error.
+type Middleware func(next Handler) Handler
+
+// UsePreSign appends hooks to the pre sign hooks of the client
+func (api *OssApi) UsePreSign(hooks ...PreSignHook) {
+	api.preSignHooks = append(api.preSignHooks, hooks...)
+}
+
+// Use appends middlewares to the chain of the client,
+// the first one added is the outermost one
+func (api *OssApi) Use(middlewares ...Middleware) {
+	api.middlewares = append(api.middlewares, middlewares...)
+}
+
+type operationKey struct{}
+
+// OperationName returns the oss api name, e.g. "PutObject",
+// from the context of a request seen by the hooks and middlewares
+func OperationName(ctx context.Context) string {
+	operation, _ := ctx.Value(operationKey{}).(string)
+	return operation
+}
+
+func (req *request) context() context.Context {
+	ctx := req.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, operationKey{}, req.operation)
+}
+
+// runPreSignHooks lets the hooks modify the headers and the query parameters of req
+func (api *OssApi) runPreSignHooks(req *request) error {
+	if len(api.preSignHooks) == 0 {
+		return nil
+	}
+	u, err := req.url()
+	if err != nil {
+		return err
+	}
+	hreq := (&http.Request{
+		Method: req.method,
+		URL:    u,
+		Header: req.headers,
+	}).WithContext(req.context())
+	for _, hook := range api.preSignHooks {
+		hook(hreq)
+	}
+	req.headers = hreq.Header
+	req.params = hreq.URL.Query()
+	return nil
+}
+
+func (api *OssApi) handler() Handler {
+	handler := api.send
+	for i := len(api.middlewares) - 1; i >= 0; i-- {
+		handler = api.middlewares[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,112 @@
+package oss
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// respond short circuits the chain with a synthetic response
+func respond(status int, body string) Middleware {
+	return func(next Handler) Handler {
+		return func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{"X-Oss-Request-Id": {"request"}},
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+				Request:    r,
+			}, nil
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(r *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+OperationName(r.Context()))
+				resp, err := next(r)
+				calls = append(calls, name+" done")
+				return resp, err
+			}
+		}
+	}
+	client.Use(trace("outer"), trace("inner"))
+	client.Use(respond(http.StatusOK, ""))
+
+	if err := client.PutObject("a.txt", []byte("hello"), "text/plain"); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	expected := "outer PutObject,inner PutObject,inner done,outer done"
+	if strings.Join(calls, ",") != expected {
+		t.Errorf("wrong middleware order expected %s, actual %s", expected, strings.Join(calls, ","))
+	}
+}
+
+func TestMiddlewareSeesErrorResponses(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var status int
+	client.Use(func(next Handler) Handler {
+		return func(r *http.Request) (*http.Response, error) {
+			resp, err := next(r)
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			return resp, err
+		}
+	})
+	client.Use(respond(http.StatusNotFound, ""))
+
+	_, err := client.GetObjectMetadata("a.txt")
+	if !IsNotFound(err) {
+		t.Errorf("wrong error %v", err)
+	}
+	if status != http.StatusNotFound {
+		t.Errorf("the middleware should see the 404 response, got %d", status)
+	}
+}
+
+func TestPreSignHookIsSigned(t *testing.T) {
+	for _, version := range []SignatureVersion{SignatureV1, SignatureV4} {
+		client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+		client.SetSignatureVersion(version)
+		logger := &recordingLogger{}
+		client.SetLogger(logger)
+		var operation string
+		client.UsePreSign(func(r *http.Request) {
+			operation = OperationName(r.Context())
+			r.Header.Set("x-oss-meta-trace", "abc")
+			q := r.URL.Query()
+			q.Set("versionId", "v1")
+			r.URL.RawQuery = q.Encode()
+		})
+		var sent *http.Request
+		client.Use(func(next Handler) Handler {
+			return func(r *http.Request) (*http.Response, error) {
+				sent = r
+				return next(r)
+			}
+		}, respond(http.StatusOK, ""))
+
+		if err := client.PutObject("a.txt", []byte("hello"), "text/plain"); err != nil {
+			t.Fatalf("cant put object %v", err)
+		}
+		if operation != "PutObject" {
+			t.Errorf("wrong operation %s", operation)
+		}
+		if sent.Header.Get("x-oss-meta-trace") != "abc" || sent.URL.Query().Get("versionId") != "v1" {
+			t.Errorf("the hook changes are not sent %v %v", sent.Header, sent.URL)
+		}
+		output := strings.Join(logger.lines, "\n")
+		if !strings.Contains(output, "x-oss-meta-trace:abc") {
+			t.Errorf("the hook header is not signed with %v: %s", version, output)
+		}
+		// v4 signs every query parameter, v1 only the sub resources
+		if version == SignatureV4 && !strings.Contains(output, "versionId=v1") {
+			t.Errorf("the hook parameter is not signed: %s", output)
+		}
+	}
+}
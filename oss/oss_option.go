@@ -0,0 +1,128 @@
+package oss
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Option customizes a single request, e.g. adds headers or query parameters
+type Option func(req *request)
+
+func (req *request) apply(options []Option) {
+	for _, option := range options {
+		option(req)
+	}
+}
+
+// setHeader replaces the header key regardless of its case
+func (req *request) setHeader(key, value string) {
+	if req.headers == nil {
+		req.headers = make(http.Header)
+	}
+	for k := range req.headers {
+		if strings.EqualFold(k, key) {
+			delete(req.headers, k)
+		}
+	}
+	req.headers[key] = []string{value}
+}
+
+func (req *request) setParam(key, value string) {
+	if req.params == nil {
+		req.params = make(map[string][]string)
+	}
+	req.params[key] = []string{value}
+}
+
+func WithHeader(key, value string) Option {
+	return func(req *request) {
+		req.setHeader(key, value)
+	}
+}
+
+func WithParam(key, value string) Option {
+	return func(req *request) {
+		req.setParam(key, value)
+	}
+}
+
+// WithContext cancels the request when ctx is done
+func WithContext(ctx context.Context) Option {
+	return func(req *request) {
+		req.ctx = ctx
+	}
+}
+
+func ContentType(value string) Option {
+	return WithHeader("Content-Type", value)
+}
+
+func ContentMD5(value string) Option {
+	return WithHeader("Content-MD5", value)
+}
+
+// the conditional options fail the reads with ErrNotModified and
+// the reads and writes with ErrPreconditionFailed if the condition is not met
+func IfMatch(etag string) Option {
+	return WithHeader("If-Match", etag)
+}
+
+func IfNoneMatch(etag string) Option {
+	return WithHeader("If-None-Match", etag)
+}
+
+func IfModifiedSince(t time.Time) Option {
+	return WithHeader("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+}
+
+func IfUnmodifiedSince(t time.Time) Option {
+	return WithHeader("If-Unmodified-Since", t.UTC().Format(http.TimeFormat))
+}
+
+// ForbidOverwrite fails the PutObject, CompleteMultipart or Copy with ErrAlreadyExists
+// if the object exists
+func ForbidOverwrite() Option {
+	return WithHeader("x-oss-forbid-overwrite", "true")
+}
+
+// the response-* options override the headers of a GET response
+func ResponseContentType(value string) Option {
+	return WithParam("response-content-type", value)
+}
+
+func ResponseContentLanguage(value string) Option {
+	return WithParam("response-content-language", value)
+}
+
+func ResponseExpires(value string) Option {
+	return WithParam("response-expires", value)
+}
+
+func ResponseCacheControl(value string) Option {
+	return WithParam("response-cache-control", value)
+}
+
+func ResponseContentDisposition(value string) Option {
+	return WithParam("response-content-disposition", value)
+}
+
+func ResponseContentEncoding(value string) Option {
+	return WithParam("response-content-encoding", value)
+}
+
+// Process applies the image/video processing style, e.g. "image/resize,w_100"
+func Process(value string) Option {
+	return WithParam("x-oss-process", value)
+}
+
+// Part addresses the part partNumber of a multipart upload,
+// e.g. to presign the PUT of a part
+func Part(context *UploadContext, partNumber int) Option {
+	return func(req *request) {
+		req.setParam("uploadId", context.UploadId)
+		req.setParam("partNumber", strconv.Itoa(partNumber))
+	}
+}
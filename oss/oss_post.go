@@ -0,0 +1,270 @@
+package oss
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+// browser form upload
+// https://help.aliyun.com/zh/oss/developer-reference/postobject
+
+const postPolicyTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// PostPolicy restricts what a browser can upload with the signed form.
+// Either Key or KeyPrefix should be set, the zero values of the other fields
+// mean no restriction.
+type PostPolicy struct {
+	Expiration          time.Time
+	Key                 string // the exact object key
+	KeyPrefix           string // the key must start with KeyPrefix
+	ContentLengthMin    int64
+	ContentLengthMax    int64 // no content length range if 0
+	ContentType         string
+	ContentTypePrefix   string // e.g. "image/"
+	SuccessActionStatus int    // 200, 201 or 204
+	Callback            string // the base64 encoded upload callback
+}
+
+// PostForm is the action url and the hidden fields of the html upload form,
+// the file field must be the last one of the form
+type PostForm struct {
+	URL    string
+	Fields map[string]string
+}
+
+// PostObjectForm signs policy with the client credentials
+func (api *OssApi) PostObjectForm(policy *PostPolicy) (*PostForm, error) {
+	creds, err := api.credentials.get()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string)
+	conditions := []interface{}{
+		map[string]string{"bucket": api.bucket},
+	}
+	if policy.Key != "" {
+		fields["key"] = noramilizeObject(policy.Key)
+		conditions = append(conditions, []string{"eq", "$key", fields["key"]})
+	}
+	if policy.KeyPrefix != "" {
+		conditions = append(conditions, []string{"starts-with", "$key", noramilizeObject(policy.KeyPrefix)})
+	}
+	if policy.ContentLengthMax > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", policy.ContentLengthMin, policy.ContentLengthMax})
+	}
+	if policy.ContentType != "" {
+		fields["Content-Type"] = policy.ContentType
+		conditions = append(conditions, []string{"eq", "$Content-Type", policy.ContentType})
+	}
+	if policy.ContentTypePrefix != "" {
+		conditions = append(conditions, []string{"starts-with", "$Content-Type", policy.ContentTypePrefix})
+	}
+	if policy.SuccessActionStatus != 0 {
+		fields["success_action_status"] = strconv.Itoa(policy.SuccessActionStatus)
+	}
+	if policy.Callback != "" {
+		fields["callback"] = policy.Callback
+	}
+	if creds.SecurityToken != "" {
+		fields["x-oss-security-token"] = creds.SecurityToken
+	}
+
+	now := time.Now().UTC()
+	if api.signatureVersion == SignatureV4 {
+		fields["x-oss-signature-version"] = signingAlgorithmV4
+		fields["x-oss-credential"] = creds.AccessKeyId + "/" + api.scopeV4(now)
+		fields["x-oss-date"] = now.Format(timeFormatV4)
+	}
+	for _, k := range []string{"success_action_status", "callback", "x-oss-security-token",
+		"x-oss-signature-version", "x-oss-credential", "x-oss-date"} {
+		if v, ok := fields[k]; ok {
+			conditions = append(conditions, map[string]string{k: v})
+		}
+	}
+
+	document, err := json.Marshal(map[string]interface{}{
+		"expiration": policy.Expiration.UTC().Format(postPolicyTimeFormat),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fields["policy"] = b64.EncodeToString(document)
+
+	if api.signatureVersion == SignatureV4 {
+		key := signingKeyV4(creds.AccessKeySecret, now, api.signRegion())
+		fields["x-oss-signature"] = hex.EncodeToString(hmacSHA256(key, fields["policy"]))
+	} else {
+		fields["OSSAccessKeyId"] = creds.AccessKeyId
+		fields["Signature"] = signPostPolicy(creds.AccessKeySecret, fields["policy"])
+	}
+
+	return &PostForm{api.baseUrl(), fields}, nil
+}
+
+func signPostPolicy(accessKeySecret, policy string) string {
+	hash := hmac.New(sha1.New, []byte(accessKeySecret))
+	hash.Write([]byte(policy))
+	return b64.EncodeToString(hash.Sum(nil))
+}
+
+// PostObject is a form upload accepted by VerifyPostObject
+type PostObject struct {
+	Key         string
+	ContentType string
+	Contents    []byte
+	Fields      map[string]string // the lower cased form fields
+}
+
+func postObjectError(code, format string, a ...interface{}) error {
+	statusCode := http.StatusForbidden
+	if code == "InvalidArgument" || code == "EntityTooLarge" || code == "EntityTooSmall" {
+		statusCode = http.StatusBadRequest
+	}
+	return &Error{StatusCode: statusCode, Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// VerifyPostObject checks the signature and the policy of a form upload the way oss does,
+// it is meant for local test servers standing in for the bucket.
+// The returned error is an *Error carrying the oss error code.
+func VerifyPostObject(r *http.Request, bucket string, creds *Credentials) (*PostObject, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, postObjectError("InvalidArgument", "not a multipart form: %v", err)
+	}
+	upload := &PostObject{Fields: make(map[string]string)}
+	found := false
+	for !found {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, postObjectError("InvalidArgument", "bad multipart form: %v", err)
+		}
+		var buffer bytes.Buffer
+		buffer.ReadFrom(part)
+		name := strings.ToLower(part.FormName())
+		if name == "file" {
+			upload.Contents = buffer.Bytes()
+			upload.Fields["key"] = strings.Replace(upload.Fields["key"], "${filename}", part.FileName(), -1)
+			if upload.Fields["content-type"] == "" {
+				upload.Fields["content-type"] = part.Header.Get("Content-Type")
+			}
+			found = true
+		} else {
+			upload.Fields[name] = buffer.String()
+		}
+	}
+	if !found {
+		return nil, postObjectError("InvalidArgument", "the file field is missing")
+	}
+	fields := upload.Fields
+	upload.Key = fields["key"]
+	upload.ContentType = fields["content-type"]
+	if upload.Key == "" {
+		return nil, postObjectError("InvalidArgument", "the key field is missing")
+	}
+	if creds.SecurityToken != "" && fields["x-oss-security-token"] != creds.SecurityToken {
+		return nil, postObjectError("InvalidAccessKeyId", "the security token is invalid")
+	}
+
+	policy := fields["policy"]
+	if fields["x-oss-signature-version"] == signingAlgorithmV4 {
+		scope := strings.SplitN(fields["x-oss-credential"], "/", 5)
+		date, err := time.Parse(timeFormatV4, fields["x-oss-date"])
+		if len(scope) != 5 || err != nil {
+			return nil, postObjectError("InvalidArgument", "bad x-oss-credential or x-oss-date")
+		}
+		if scope[0] != creds.AccessKeyId {
+			return nil, postObjectError("InvalidAccessKeyId", "unknown access key id %s", scope[0])
+		}
+		key := signingKeyV4(creds.AccessKeySecret, date, scope[2])
+		if !hmac.Equal([]byte(fields["x-oss-signature"]), []byte(hex.EncodeToString(hmacSHA256(key, policy)))) {
+			return nil, postObjectError("SignatureDoesNotMatch", "the form signature does not match")
+		}
+	} else {
+		if fields["ossaccesskeyid"] != creds.AccessKeyId {
+			return nil, postObjectError("InvalidAccessKeyId", "unknown access key id %s", fields["ossaccesskeyid"])
+		}
+		if !hmac.Equal([]byte(fields["signature"]), []byte(signPostPolicy(creds.AccessKeySecret, policy))) {
+			return nil, postObjectError("SignatureDoesNotMatch", "the form signature does not match")
+		}
+	}
+
+	data, err := b64.DecodeString(policy)
+	if err != nil {
+		return nil, postObjectError("InvalidPolicyDocument", "the policy is not base64 encoded")
+	}
+	var document struct {
+		Expiration string
+		Conditions []interface{}
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, postObjectError("InvalidPolicyDocument", "the policy is not valid json: %v", err)
+	}
+	expiration, err := time.Parse(postPolicyTimeFormat, document.Expiration)
+	if err != nil {
+		return nil, postObjectError("InvalidPolicyDocument", "bad expiration %q", document.Expiration)
+	}
+	if time.Now().After(expiration) {
+		return nil, postObjectError("AccessDenied", "the policy expired at %s", document.Expiration)
+	}
+
+	value := func(name string) string {
+		name = strings.ToLower(strings.TrimPrefix(name, "$"))
+		if name == "bucket" {
+			return bucket
+		}
+		return fields[name]
+	}
+	for _, condition := range document.Conditions {
+		switch c := condition.(type) {
+		case map[string]interface{}:
+			for k, v := range c {
+				if value(k) != fmt.Sprint(v) {
+					return nil, postObjectError("AccessDenied", "invalid according to policy: %s must be %v", k, v)
+				}
+			}
+		case []interface{}:
+			if len(c) != 3 {
+				return nil, postObjectError("InvalidPolicyDocument", "bad condition %v", c)
+			}
+			op, _ := c[0].(string)
+			switch strings.ToLower(op) {
+			case "eq":
+				if value(fmt.Sprint(c[1])) != fmt.Sprint(c[2]) {
+					return nil, postObjectError("AccessDenied", "invalid according to policy: %v must be %v", c[1], c[2])
+				}
+			case "starts-with":
+				if !strings.HasPrefix(value(fmt.Sprint(c[1])), fmt.Sprint(c[2])) {
+					return nil, postObjectError("AccessDenied", "invalid according to policy: %v must start with %v", c[1], c[2])
+				}
+			case "content-length-range":
+				min, _ := c[1].(float64)
+				max, _ := c[2].(float64)
+				if size := float64(len(upload.Contents)); size < min {
+					return nil, postObjectError("EntityTooSmall", "the file is smaller than %v bytes", min)
+				} else if size > max {
+					return nil, postObjectError("EntityTooLarge", "the file is larger than %v bytes", max)
+				}
+			default:
+				return nil, postObjectError("InvalidPolicyDocument", "unknown condition %v", op)
+			}
+		default:
+			return nil, postObjectError("InvalidPolicyDocument", "bad condition %v", c)
+		}
+	}
+	return upload, nil
+}
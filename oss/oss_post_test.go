@@ -0,0 +1,115 @@
+package oss
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postForm(t *testing.T, form *PostForm, filename string, contents []byte) *http.Response {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for k, v := range form.Fields {
+		writer.WriteField(k, v)
+	}
+	file, _ := writer.CreateFormFile("file", filename)
+	file.Write(contents)
+	writer.Close()
+	resp, err := http.Post(form.URL, writer.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("cant post form %v", err)
+	}
+	return resp
+}
+
+func TestPostObjectForm(t *testing.T) {
+	creds := &Credentials{AccessKeyId: "ak", AccessKeySecret: "sk", SecurityToken: "token"}
+	var received *PostObject
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upload, err := VerifyPostObject(r, "bucket", creds)
+		if err != nil {
+			ossErr := err.(*Error)
+			http.Error(w, ossErr.Code+": "+ossErr.Message, ossErr.StatusCode)
+			return
+		}
+		received = upload
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewWithSecurityToken("oss-cn-hangzhou", creds.AccessKeyId, creds.AccessKeySecret, creds.SecurityToken, "bucket", false)
+	policy := &PostPolicy{
+		Expiration:        time.Now().Add(time.Minute),
+		KeyPrefix:         "/uploads/",
+		ContentLengthMax:  1024,
+		ContentTypePrefix: "image/",
+	}
+
+	for _, version := range []SignatureVersion{SignatureV1, SignatureV4} {
+		client.SetSignatureVersion(version)
+		form, err := client.PostObjectForm(policy)
+		if err != nil {
+			t.Fatalf("cant build post form %v", err)
+		}
+		if form.URL != "http://bucket.oss-cn-hangzhou.aliyuncs.com" {
+			t.Errorf("wrong form url %s", form.URL)
+		}
+		form.URL = server.URL
+		form.Fields["key"] = "uploads/${filename}"
+		form.Fields["Content-Type"] = "image/png"
+
+		received = nil
+		resp := postForm(t, form, "a.png", []byte("png"))
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("wrong status code expected %d, actual %d %s", http.StatusNoContent, resp.StatusCode, body)
+		}
+		if received.Key != "uploads/a.png" || received.ContentType != "image/png" || string(received.Contents) != "png" {
+			t.Errorf("wrong received upload %#v", received)
+		}
+	}
+
+	client.SetSignatureVersion(SignatureV1)
+	cases := []struct {
+		name    string
+		modify  func(form *PostForm)
+		size    int
+		message string
+	}{
+		{"key prefix", func(form *PostForm) { form.Fields["key"] = "other/a.png" }, 10, "AccessDenied"},
+		{"content type", func(form *PostForm) { form.Fields["Content-Type"] = "text/html" }, 10, "AccessDenied"},
+		{"content length", func(form *PostForm) {}, 2048, "EntityTooLarge"},
+		{"signature", func(form *PostForm) { form.Fields["Signature"] = signPostPolicy("other", form.Fields["policy"]) }, 10, "SignatureDoesNotMatch"},
+		{"token", func(form *PostForm) { delete(form.Fields, "x-oss-security-token") }, 10, "InvalidAccessKeyId"},
+	}
+	for _, c := range cases {
+		form, _ := client.PostObjectForm(policy)
+		form.URL = server.URL
+		form.Fields["key"] = "uploads/a.png"
+		form.Fields["Content-Type"] = "image/png"
+		c.modify(form)
+		resp := postForm(t, form, "a.png", bytes.Repeat([]byte("a"), c.size))
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 400 || !strings.HasPrefix(string(body), c.message) {
+			t.Errorf("%s should be rejected with %s, actual %d %s", c.name, c.message, resp.StatusCode, body)
+		}
+	}
+
+	policy.Expiration = time.Now().Add(-time.Minute)
+	form, _ := client.PostObjectForm(policy)
+	form.URL = server.URL
+	form.Fields["key"] = "uploads/a.png"
+	form.Fields["Content-Type"] = "image/png"
+	resp := postForm(t, form, "a.png", []byte("png"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expired policy should be rejected, actual %d", resp.StatusCode)
+	}
+}
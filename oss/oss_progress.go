@@ -0,0 +1,143 @@
+package oss
+
+import (
+	"io"
+	"strconv"
+)
+
+type ProgressEventType int
+
+const (
+	TransferStarted ProgressEventType = iota
+	TransferData
+	TransferCompleted
+	TransferFailed
+	PartStarted
+	PartCompleted
+	PartFailed
+)
+
+// ProgressEvent reports the transfer of an object or of a part of a multipart upload or copy.
+// PartNumber is 0 for single transfers, TotalBytes is -1 if the size is not known.
+type ProgressEvent struct {
+	Type          ProgressEventType
+	PartNumber    int
+	ConsumedBytes int64
+	TotalBytes    int64
+	RwBytes       int64 // the bytes transferred since the previous event
+	Err           error
+}
+
+// ProgressListener is called synchronously from the transfer, it should return quickly
+type ProgressListener func(event *ProgressEvent)
+
+// Progress reports the transfer of PutObject, UploadMultipart, GetObjectRange and Copy
+func Progress(listener ProgressListener) Option {
+	return func(req *request) {
+		req.progress = listener
+	}
+}
+
+// progressOf returns the listener set by options
+func progressOf(options []Option) ProgressListener {
+	req := &request{}
+	req.apply(options)
+	return req.progress
+}
+
+type progressTracker struct {
+	listener   ProgressListener
+	partNumber int
+	consumed   int64
+	total      int64
+	done       bool
+}
+
+// newProgressTracker returns nil if req has no listener,
+// all methods of a nil tracker are no-op
+func newProgressTracker(req *request) *progressTracker {
+	if req.progress == nil {
+		return nil
+	}
+	partNumber, _ := strconv.Atoi(req.params.Get("partNumber"))
+	return &progressTracker{listener: req.progress, partNumber: partNumber, total: -1}
+}
+
+func (t *progressTracker) publish(eventType ProgressEventType, rwBytes int64, err error) {
+	if t.partNumber != 0 {
+		switch eventType {
+		case TransferStarted:
+			eventType = PartStarted
+		case TransferCompleted:
+			eventType = PartCompleted
+		case TransferFailed:
+			eventType = PartFailed
+		}
+	}
+	t.listener(&ProgressEvent{
+		Type:          eventType,
+		PartNumber:    t.partNumber,
+		ConsumedBytes: t.consumed,
+		TotalBytes:    t.total,
+		RwBytes:       rwBytes,
+		Err:           err,
+	})
+}
+
+func (t *progressTracker) started(total int64) {
+	if t == nil {
+		return
+	}
+	t.total = total
+	t.publish(TransferStarted, 0, nil)
+}
+
+func (t *progressTracker) transferred(n int64) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.consumed += n
+	t.publish(TransferData, n, nil)
+}
+
+func (t *progressTracker) completed() {
+	if t == nil || t.done {
+		return
+	}
+	t.done = true
+	t.publish(TransferCompleted, 0, nil)
+}
+
+func (t *progressTracker) failed(err error) {
+	if t == nil || t.done {
+		return
+	}
+	t.done = true
+	t.publish(TransferFailed, 0, err)
+}
+
+// progressReader reports the bytes read from the request or the response body,
+// a response body completes the transfer at EOF
+type progressReader struct {
+	io.Reader
+	tracker  *progressTracker
+	response bool
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.tracker.transferred(int64(n))
+	if err == io.EOF {
+		if r.response {
+			r.tracker.completed()
+		}
+	} else if err != nil {
+		r.tracker.failed(err)
+	}
+	return n, err
+}
+
+type progressReadCloser struct {
+	progressReader
+	io.Closer
+}
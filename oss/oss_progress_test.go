@@ -0,0 +1,175 @@
+package oss
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// serve short circuits the chain with the response of handler
+func serve(handler func(r *http.Request, body []byte) *http.Response) Middleware {
+	return func(next Handler) Handler {
+		return func(r *http.Request) (*http.Response, error) {
+			var body []byte
+			if r.Body != nil {
+				body, _ = ioutil.ReadAll(r.Body)
+			}
+			resp := handler(r, body)
+			if resp.Header == nil {
+				resp.Header = make(http.Header)
+			}
+			if resp.Body == nil {
+				resp.Body = ioutil.NopCloser(strings.NewReader(""))
+			}
+			resp.Request = r
+			return resp, nil
+		}
+	}
+}
+
+type progressRecorder struct {
+	events []ProgressEvent
+}
+
+func (r *progressRecorder) listener(event *ProgressEvent) {
+	r.events = append(r.events, *event)
+}
+
+func (r *progressRecorder) types() []ProgressEventType {
+	var types []ProgressEventType
+	for _, event := range r.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestPutObjectProgress(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		return &http.Response{StatusCode: http.StatusOK}
+	}))
+	recorder := &progressRecorder{}
+	contents := []byte("hello world")
+	if err := client.PutObject("a.txt", contents, "text/plain", Progress(recorder.listener)); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	types := recorder.types()
+	if types[0] != TransferStarted || types[len(types)-1] != TransferCompleted {
+		t.Errorf("wrong events %v", types)
+	}
+	last := recorder.events[len(recorder.events)-1]
+	if last.ConsumedBytes != int64(len(contents)) || last.TotalBytes != int64(len(contents)) {
+		t.Errorf("wrong bytes %#v", last)
+	}
+	var rwBytes int64
+	for _, event := range recorder.events {
+		rwBytes += event.RwBytes
+	}
+	if rwBytes != int64(len(contents)) {
+		t.Errorf("the data events sum up to %d", rwBytes)
+	}
+}
+
+func TestUploadMultipartProgress(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		if r.URL.Query().Get("partNumber") == "2" {
+			return &http.Response{StatusCode: http.StatusInternalServerError}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": {"etag"}}}
+	}))
+	context := &UploadContext{Key: "a.txt", UploadId: "upload"}
+	recorder := &progressRecorder{}
+	if err := client.UploadMultipart(context, []byte("part1"), 1, Progress(recorder.listener)); err != nil {
+		t.Fatalf("cant upload part %v", err)
+	}
+	if err := client.UploadMultipart(context, []byte("part2"), 2, Progress(recorder.listener)); err == nil {
+		t.Fatalf("the part 2 should fail")
+	}
+	expected := []ProgressEventType{PartStarted, TransferData, PartCompleted, PartStarted, TransferData, PartFailed}
+	if !reflect.DeepEqual(recorder.types(), expected) {
+		t.Errorf("wrong events expected %v, actual %v", expected, recorder.types())
+	}
+	for _, event := range recorder.events[3:] {
+		if event.PartNumber != 2 {
+			t.Errorf("wrong part number %#v", event)
+		}
+	}
+	if recorder.events[5].Err == nil {
+		t.Errorf("the failed event should have the error")
+	}
+}
+
+func TestGetObjectRangeProgress(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			ContentLength: 11,
+			Body:          ioutil.NopCloser(strings.NewReader("hello world")),
+		}
+	}))
+	recorder := &progressRecorder{}
+	r, _, err := client.GetObjectRange("a.txt", -1, -1, Progress(recorder.listener))
+	if err != nil {
+		t.Fatalf("cant get object %v", err)
+	}
+	if types := recorder.types(); len(types) != 1 || types[0] != TransferStarted {
+		t.Errorf("the download should only start before reading the body %v", types)
+	}
+	if string(r.Bytes()) != "hello world" {
+		t.Errorf("wrong contents %s", r.Bytes())
+	}
+	r.Close()
+	last := recorder.events[len(recorder.events)-1]
+	if last.Type != TransferCompleted || last.ConsumedBytes != 11 || last.TotalBytes != 11 {
+		t.Errorf("wrong last event %#v", last)
+	}
+}
+
+func TestCopyProgress(t *testing.T) {
+	const size = 250 * 1024
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		query := r.URL.Query()
+		if _, ok := query["uploads"]; ok {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>")),
+			}
+		}
+		if query.Get("partNumber") == "" {
+			return &http.Response{StatusCode: http.StatusOK}
+		}
+		var start, end int64
+		// the oss headers are sent in lower case
+		n, _ := fmt.Sscanf(r.Header["x-oss-copy-source-range"][0], "bytes=%d-%d", &start, &end)
+		if n < 2 || end >= size {
+			end = size - 1
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", start, end, size)}},
+			Body:       ioutil.NopCloser(strings.NewReader("<CopyPartResult><ETag>etag</ETag></CopyPartResult>")),
+		}
+	}))
+	recorder := &progressRecorder{}
+	if err := client.Copy("", "a.txt", "b.txt", "text/plain", 100*1024, Progress(recorder.listener)); err != nil {
+		t.Fatalf("cant copy %v", err)
+	}
+	expected := []ProgressEventType{TransferStarted,
+		PartStarted, PartCompleted, PartStarted, PartCompleted, PartStarted, PartCompleted,
+		TransferCompleted}
+	if !reflect.DeepEqual(recorder.types(), expected) {
+		t.Fatalf("wrong events expected %v, actual %v", expected, recorder.types())
+	}
+	for i, consumed := range []int64{100 * 1024, 200 * 1024, size} {
+		event := recorder.events[2+2*i]
+		if event.PartNumber != i+1 || event.ConsumedBytes != consumed || event.TotalBytes != size {
+			t.Errorf("wrong part event %#v", event)
+		}
+	}
+}
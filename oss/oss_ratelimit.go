@@ -0,0 +1,122 @@
+package oss
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket of bytes, refilled at rate bytes per second
+// up to one second of burst
+type rateLimiter struct {
+	sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// burst is the largest read allowed in one go
+func (l *rateLimiter) burst() int {
+	if l.rate < 1 {
+		return 1
+	}
+	return int(l.rate)
+}
+
+// wait takes n tokens and blocks until the bucket is no longer in debt
+func (l *rateLimiter) wait(n int) {
+	l.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.Unlock()
+	if delay > 0 {
+		l.sleep(delay)
+	}
+}
+
+// limitedReader throttles the reads by all the limiters
+type limitedReader struct {
+	io.Reader
+	limiters []*rateLimiter
+}
+
+func newLimitedReader(r io.Reader, limiters ...*rateLimiter) io.Reader {
+	var active []*rateLimiter
+	for _, limiter := range limiters {
+		if limiter != nil {
+			active = append(active, limiter)
+		}
+	}
+	if len(active) == 0 {
+		return r
+	}
+	return &limitedReader{r, active}
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	for _, limiter := range r.limiters {
+		if burst := limiter.burst(); len(p) > burst {
+			p = p[:burst]
+		}
+	}
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		for _, limiter := range r.limiters {
+			limiter.wait(n)
+		}
+	}
+	return n, err
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// SetRateLimit throttles the request and the response bodies of the client
+// to upload and download bytes per second, shared by the concurrent requests.
+// 0 disables the throttling.
+func (api *OssApi) SetRateLimit(upload, download int64) {
+	api.uploadLimiter = newRateLimiter(upload)
+	api.downloadLimiter = newRateLimiter(download)
+}
+
+// RateLimit throttles the body of the request, e.g. the contents of PutObject
+// or of the parts, or the response of GetObjectRange, in addition to the client limit
+func RateLimit(bytesPerSecond int64) Option {
+	return func(req *request) {
+		req.limiter = newRateLimiter(bytesPerSecond)
+	}
+}
+
+// TrafficLimit asks oss to throttle the transfer to bitsPerSecond,
+// oss accepts 819200 (100KB/s) to 838860800 (100MB/s)
+func TrafficLimit(bitsPerSecond int64) Option {
+	return WithHeader("x-oss-traffic-limit", strconv.FormatInt(bitsPerSecond, 10))
+}
@@ -0,0 +1,95 @@
+package oss
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock advances when the limiter sleeps
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) install(l *rateLimiter) {
+	l.now = func() time.Time { return c.now }
+	l.sleep = func(d time.Duration) {
+		c.slept += d
+		c.now = c.now.Add(d)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	limiter := newRateLimiter(1000)
+	clock.install(limiter)
+
+	data, err := ioutil.ReadAll(newLimitedReader(bytes.NewReader(make([]byte, 3500)), limiter))
+	if err != nil || len(data) != 3500 {
+		t.Fatalf("cant read %d %v", len(data), err)
+	}
+	// the first second is the burst
+	if clock.slept != 2500*time.Millisecond {
+		t.Errorf("wrong throttling %v", clock.slept)
+	}
+
+	clock.now = clock.now.Add(10 * time.Second)
+	clock.slept = 0
+	limiter.wait(1000)
+	if clock.slept != 0 {
+		t.Errorf("the bucket should be refilled after idle %v", clock.slept)
+	}
+}
+
+func TestRateLimitRequests(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.SetRateLimit(1000, 2000)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	clock.install(client.uploadLimiter)
+	clock.install(client.downloadLimiter)
+	var trafficLimit []string
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		trafficLimit = r.Header["x-oss-traffic-limit"]
+		if r.Method == "GET" {
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(strings.Repeat("a", 6000)))}
+		}
+		return &http.Response{StatusCode: http.StatusOK}
+	}))
+
+	if err := client.PutObject("a.txt", make([]byte, 3000), "text/plain", TrafficLimit(819200)); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	if clock.slept != 2*time.Second {
+		t.Errorf("wrong upload throttling %v", clock.slept)
+	}
+	if len(trafficLimit) != 1 || trafficLimit[0] != "819200" {
+		t.Errorf("wrong traffic limit header %v", trafficLimit)
+	}
+
+	clock.slept = 0
+	if _, err := client.GetObject("a.txt"); err != nil {
+		t.Fatalf("cant get object %v", err)
+	}
+	if clock.slept != 2*time.Second {
+		t.Errorf("wrong download throttling %v", clock.slept)
+	}
+
+	// the request limit is stricter than the client one
+	clock.slept = 0
+	var requestClock fakeClock
+	option := func(req *request) {
+		RateLimit(500)(req)
+		requestClock.now = clock.now
+		requestClock.install(req.limiter)
+	}
+	if err := client.PutObject("a.txt", make([]byte, 1500), "text/plain", option); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	if requestClock.slept != 2*time.Second {
+		t.Errorf("wrong request throttling %v", requestClock.slept)
+	}
+}
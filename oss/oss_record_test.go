@@ -0,0 +1,68 @@
+package oss
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/topikachu/oss-mini-go-sdk/oss/ossrecord"
+)
+
+var record = flag.Bool("record", false, "record the cassettes of the golden tests")
+
+func TestCassette(t *testing.T) {
+	path := filepath.Join("testdata", "cassettes", "objects.json")
+	client := New(config.Region, config.AccessKeyId, config.AccessKeySecret, config.Bucket, true)
+	var replayer *ossrecord.Replayer
+	if *record {
+		if config.Endpoint != "" {
+			t.Skip("the cassette is recorded against a real bucket, set the OSS_* variables")
+		}
+		recorder := ossrecord.NewRecorder(path, nil)
+		client.SetTransport(recorder)
+		defer func() {
+			if err := recorder.Save(); err != nil {
+				t.Errorf("cant save the cassette %v", err)
+			}
+		}()
+	} else {
+		var err error
+		if replayer, err = ossrecord.NewReplayer(path); os.IsNotExist(err) {
+			t.Skip("no cassette, record it with -record and the OSS_* variables")
+		} else if err != nil {
+			t.Fatalf("cant load the cassette %v", err)
+		}
+		client.SetTransport(replayer)
+	}
+
+	hello := []byte("hello cassette")
+	if err := client.PutObject("cassette/hello.txt", hello, "text/plain"); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	received, err := client.GetObject("cassette/hello.txt")
+	if err != nil || !bytes.Equal(received, hello) {
+		t.Errorf("wrong object %s %v", received, err)
+	}
+	context, err := client.InitMultipartUpload("cassette/multipart.txt", "text/plain")
+	if err != nil {
+		t.Fatalf("cant init multi upload %v", err)
+	}
+	if err := client.UploadMultipart(context, hello, 1); err != nil {
+		t.Fatalf("cant upload multi part %v", err)
+	}
+	if err := client.CompleteMultipart(context); err != nil {
+		t.Fatalf("cant complete multi part %v", err)
+	}
+	files, _, _, err := client.ListFiles("cassette/", "/", "", -1)
+	if err != nil || len(files) != 2 {
+		t.Errorf("wrong files %v %v", files, err)
+	}
+	if err := client.Delete("cassette/hello.txt", "cassette/multipart.txt"); err != nil {
+		t.Errorf("cant delete %v", err)
+	}
+	if replayer != nil && replayer.Unused() != 0 {
+		t.Errorf("%d interactions are not replayed", replayer.Unused())
+	}
+}
@@ -0,0 +1,124 @@
+package oss
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// the retrieval tiers of RestoreObject, from the fastest to the cheapest,
+// they apply to the ColdArchive objects only
+const (
+	RestoreExpedited = "Expedited"
+	RestoreStandard  = "Standard"
+	RestoreBulk      = "Bulk"
+)
+
+type restoreRequest struct {
+	XMLName xml.Name `xml:"RestoreRequest"`
+	Days    int      `xml:"Days"`
+	Tier    string   `xml:"JobParameters>Tier,omitempty"`
+}
+
+// RestoreObject makes the Archive or ColdArchive object readable for days,
+// 0 days and an empty tier use the defaults of oss.
+// it fails with ErrRestoreInProgress until the previous restore completes
+func (api *OssApi) RestoreObject(object string, days int, tier string, options ...Option) error {
+	req := &request{
+		operation: "RestoreObject",
+		method:    "POST",
+		object:    noramilizeObject(object),
+		params: map[string][]string{
+			"restore": {""},
+		},
+	}
+	if days > 0 || tier != "" {
+		data, err := xml.Marshal(&restoreRequest{Days: days, Tier: tier})
+		if err != nil {
+			return err
+		}
+		req.payload = data
+	}
+	req.apply(options)
+	return api.query(req, nil)
+}
+
+// RestoreStatus is the x-oss-restore header of an archived object
+type RestoreStatus struct {
+	Ongoing    bool
+	ExpiryDate time.Time // when the restored copy is removed, zero while ongoing
+}
+
+var restoreField = regexp.MustCompile(`([a-z-]+)="([^"]*)"`)
+
+// GetStorageClass returns the x-oss-storage-class, e.g. StorageArchive
+func (header *Header) GetStorageClass() StorageClass {
+	return StorageClass(header.Get("x-oss-storage-class"))
+}
+
+// GetRestoreStatus returns nil if the object has never been restored
+func (header *Header) GetRestoreStatus() (*RestoreStatus, error) {
+	value := header.Get("x-oss-restore")
+	if value == "" {
+		return nil, nil
+	}
+	status := &RestoreStatus{}
+	for _, field := range restoreField.FindAllStringSubmatch(value, -1) {
+		switch field[1] {
+		case "ongoing-request":
+			status.Ongoing = field[2] == "true"
+		case "expiry-date":
+			expiry, err := http.ParseTime(field[2])
+			if err != nil {
+				return nil, err
+			}
+			status.ExpiryDate = expiry
+		}
+	}
+	return status, nil
+}
+
+// WaitForRestore checks the restore status of object every interval until
+// the restore completes or ctx is done, it returns the status of the completed restore.
+// It fails with ErrNotRestored if the object was never restored
+func (api *OssApi) WaitForRestore(ctx context.Context, object string, interval time.Duration) (*RestoreStatus, error) {
+	for {
+		header, err := api.GetObjectMetadata(object, WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		status, err := header.GetRestoreStatus()
+		if err != nil {
+			return nil, err
+		}
+		if status == nil {
+			return nil, ErrNotRestored
+		}
+		if !status.Ongoing {
+			return status, nil
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// ObjectStorageClass sets the storage class of the object written by PutObject,
+// InitMultipartUpload, Copy or CopyObject
+func ObjectStorageClass(class StorageClass) Option {
+	return WithHeader("x-oss-storage-class", string(class))
+}
+
+// SetObjectStorageClass changes the storage class of object by copying it onto itself,
+// the metadata and the tags are kept. an Archive or ColdArchive object must be restored first.
+// The single request copy is limited to the objects up to 1GB by oss
+func (api *OssApi) SetObjectStorageClass(object string, class StorageClass, options ...Option) error {
+	options = append([]Option{ObjectStorageClass(class), MetadataDirective(DirectiveCopy)}, options...)
+	return api.CopyObject(api.bucket, object, object, options...)
+}
@@ -0,0 +1,119 @@
+package oss
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestoreObject(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var bodies []string
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		if len(r.URL.Query()["restore"]) == 0 {
+			t.Errorf("missing restore sub resource %s", r.URL)
+		}
+		bodies = append(bodies, string(body))
+		if len(bodies) == 3 {
+			return &http.Response{StatusCode: http.StatusConflict, Body: ioutil.NopCloser(strings.NewReader("<Error><Code>RestoreAlreadyInProgress</Code></Error>"))}
+		}
+		return &http.Response{StatusCode: http.StatusAccepted}
+	}))
+	if err := client.RestoreObject("cold.log", 3, RestoreBulk); err != nil {
+		t.Fatalf("cant restore %v", err)
+	}
+	if err := client.RestoreObject("archive.log", 0, ""); err != nil {
+		t.Fatalf("cant restore %v", err)
+	}
+	expected := "<RestoreRequest><Days>3</Days><JobParameters><Tier>Bulk</Tier></JobParameters></RestoreRequest>"
+	if bodies[0] != expected || bodies[1] != "" {
+		t.Errorf("wrong restore requests %v", bodies)
+	}
+	if err := client.RestoreObject("cold.log", 3, RestoreBulk); !IsRestoreInProgress(err) {
+		t.Errorf("expected the restore in progress %v", err)
+	}
+}
+
+func TestRestoreStatus(t *testing.T) {
+	header := &Header{http.Header{}}
+	if status, err := header.GetRestoreStatus(); status != nil || err != nil {
+		t.Errorf("the object is never restored %v %v", status, err)
+	}
+	header.Set("x-oss-restore", `ongoing-request="true"`)
+	if status, err := header.GetRestoreStatus(); err != nil || !status.Ongoing {
+		t.Errorf("the restore should be ongoing %v %v", status, err)
+	}
+	header.Set("x-oss-restore", `ongoing-request="false", expiry-date="Sun, 16 Apr 2017 08:12:33 GMT"`)
+	status, err := header.GetRestoreStatus()
+	if err != nil || status.Ongoing || !status.ExpiryDate.Equal(time.Date(2017, 4, 16, 8, 12, 33, 0, time.UTC)) {
+		t.Errorf("wrong completed restore %v %v", status, err)
+	}
+}
+
+func TestWaitForRestore(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	heads := 0
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		heads++
+		restore := `ongoing-request="true"`
+		if heads == 3 {
+			restore = `ongoing-request="false", expiry-date="Sun, 16 Apr 2017 08:12:33 GMT"`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Oss-Restore": {restore}}}
+	}))
+	status, err := client.WaitForRestore(context.Background(), "cold.log", time.Millisecond)
+	if err != nil || status.Ongoing || heads != 3 {
+		t.Errorf("wrong restore %v %v after %d heads", status, err, heads)
+	}
+
+	heads = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.WaitForRestore(ctx, "cold.log", time.Hour); err != context.DeadlineExceeded {
+		t.Errorf("the wait should be canceled %v", err)
+	}
+}
+
+func TestWaitForRestoreNeverRestored(t *testing.T) {
+	client, server := newFakeClient(t)
+	client.PutObject("standard.log", []byte("hello"), "text/plain")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.WaitForRestore(ctx, "standard.log", time.Millisecond); !IsNotRestored(err) {
+		t.Errorf("expected the object not restored %v", err)
+	}
+	server.SetObject("cold.log", []byte("hello"), http.Header{
+		"X-Oss-Storage-Class": {"ColdArchive"},
+		"X-Oss-Restore":       {`ongoing-request="false", expiry-date="Sun, 16 Apr 2017 08:12:33 GMT"`},
+	})
+	if status, err := client.WaitForRestore(ctx, "cold.log", time.Millisecond); err != nil || status.Ongoing {
+		t.Errorf("wrong restore %v %v", status, err)
+	}
+}
+
+func TestNotRestored(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	client.Use(respond(http.StatusForbidden, "<Error><Code>InvalidObjectState</Code></Error>"))
+	if _, err := client.GetObject("cold.log"); !IsNotRestored(err) {
+		t.Errorf("expected the object not restored %v", err)
+	}
+}
+
+func TestSetObjectStorageClass(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var header http.Header
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		header = r.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("<CopyObjectResult/>"))}
+	}))
+	if err := client.SetObjectStorageClass("/a.log", StorageIA); err != nil {
+		t.Fatalf("cant change the storage class %v", err)
+	}
+	if header["x-oss-copy-source"][0] != "/bucket/a.log" || header["x-oss-storage-class"][0] != "IA" ||
+		header["x-oss-metadata-directive"][0] != "COPY" {
+		t.Errorf("wrong copy headers %v", header)
+	}
+}
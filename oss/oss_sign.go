@@ -3,10 +3,16 @@ package oss
 import (
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
-	log "github.com/Sirupsen/logrus"
+	"encoding/hex"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/topikachu/oss-mini-go-sdk/oss/internal/signing"
 )
 
 var b64 = base64.StdEncoding
@@ -18,26 +24,7 @@ var b64 = base64.StdEncoding
 // reference from the oss java sdk
 // http://docs.aliyun.com/?spm=5176.383663.9.5.ZTrRme#/pub/oss/sdk/sdk-download&java
 
-var ossSubResourceList = map[string]bool{
-	"acl":                          true,
-	"uploads":                      true,
-	"location":                     true,
-	"cors":                         true,
-	"logging":                      true,
-	"website":                      true,
-	"referer":                      true,
-	"lifecycle":                    true,
-	"delete":                       true,
-	"uploadId":                     true,
-	"partNumber":                   true,
-	"security-token":               true,
-	"response-cache-control":       true,
-	"response-content-disposition": true,
-	"response-content-encoding":    true,
-	"response-content-language":    true,
-	"response-content-type":        true,
-	"response-expires":             true,
-}
+var ossSubResourceList = signing.SubResources
 
 func getSortedKeySlice(m map[string][]string) []string {
 	keys := make([]string, len(m))
@@ -48,7 +35,7 @@ func getSortedKeySlice(m map[string][]string) []string {
 	return keys
 }
 
-func (api *OssApi) sign(req *request) string {
+func (api *OssApi) sign(req *request, creds *Credentials) string {
 	var md5, ctype, date, xoss string
 	var xossHeaders, ossSubResources []string
 	if req.headers == nil {
@@ -102,13 +89,185 @@ func (api *OssApi) sign(req *request) string {
 	}
 
 	payload := req.method + "\n" + md5 + "\n" + ctype + "\n" + date + "\n" + xoss + canonicalPath
-	hash := hmac.New(sha1.New, []byte(api.accessKeySecret))
+	hash := hmac.New(sha1.New, []byte(creds.AccessKeySecret))
 	hash.Write([]byte(payload))
 	signature := make([]byte, b64.EncodedLen(hash.Size()))
 	b64.Encode(signature, hash.Sum(nil))
-	req.headers["Authorization"] = []string{"OSS " + api.accessKeyId + ":" + string(signature)}
+	req.headers["Authorization"] = []string{"OSS " + creds.AccessKeyId + ":" + string(signature)}
 
-	log.Debugf("Signature payload: %q", payload)
-	log.Debugf("Signature: %q", signature)
+	api.logger.Debug("oss signature", "payload", redactToken(payload, creds))
 	return string(signature)
 }
+
+// ----------------------------------------------------------------------------
+// ali oss signature version 4
+// https://help.aliyun.com/zh/oss/developer-reference/recommend-to-use-signature-version-4
+
+type SignatureVersion int
+
+const (
+	SignatureV1 SignatureVersion = iota // the legacy "OSS accessKeyId:signature" hmac-sha1 scheme
+	SignatureV4                         // OSS4-HMAC-SHA256
+)
+
+const (
+	signingAlgorithmV4  = "OSS4-HMAC-SHA256"
+	unsignedPayload     = "UNSIGNED-PAYLOAD"
+	timeFormatV4        = "20060102T150405Z"
+	shortTimeFormatV4   = "20060102"
+	signingProductV4    = "oss"
+	signingTerminatorV4 = "aliyun_v4_request"
+)
+
+// SetSignatureVersion selects the signing scheme of the requests and presigned urls.
+// additionalHeaders are extra request headers signed by SignatureV4,
+// e.g. "host", they are ignored by SignatureV1.
+func (api *OssApi) SetSignatureVersion(version SignatureVersion, additionalHeaders ...string) {
+	api.signatureVersion = version
+	api.additionalHeaders = nil
+	for _, h := range additionalHeaders {
+		h = strings.ToLower(h)
+		if h != "content-type" && h != "content-md5" && !strings.HasPrefix(h, "x-oss-") {
+			api.additionalHeaders = append(api.additionalHeaders, h)
+		}
+	}
+	sort.Strings(api.additionalHeaders)
+}
+
+// signRegion turns the endpoint region "oss-cn-hangzhou-internal" into "cn-hangzhou"
+func (api *OssApi) signRegion() string {
+	region := strings.TrimPrefix(api.region, "oss-")
+	return strings.TrimSuffix(region, "-internal")
+}
+
+// presentAdditionalHeaders returns the additional headers sent with req
+func (api *OssApi) presentAdditionalHeaders(req *request) []string {
+	var present []string
+	for _, h := range api.additionalHeaders {
+		for k := range req.headers {
+			if strings.ToLower(k) == h {
+				present = append(present, h)
+				break
+			}
+		}
+	}
+	return present
+}
+
+// signV4 sets the x-oss-date, x-oss-content-sha256 and Authorization headers of req
+func (api *OssApi) signV4(req *request, creds *Credentials, now time.Time) string {
+	if req.headers == nil {
+		req.headers = make(map[string][]string)
+	}
+	now = now.UTC()
+	req.headers["x-oss-date"] = []string{now.Format(timeFormatV4)}
+	if len(req.headers["x-oss-content-sha256"]) == 0 {
+		req.headers["x-oss-content-sha256"] = []string{unsignedPayload}
+	}
+	additionalHeaders := api.presentAdditionalHeaders(req)
+	signature := api.signatureV4(req, creds, now, additionalHeaders)
+
+	credential := creds.AccessKeyId + "/" + api.scopeV4(now)
+	authorization := signingAlgorithmV4 + " Credential=" + credential
+	if len(additionalHeaders) > 0 {
+		authorization += ",AdditionalHeaders=" + strings.Join(additionalHeaders, ";")
+	}
+	authorization += ",Signature=" + signature
+	req.headers["Authorization"] = []string{authorization}
+	return signature
+}
+
+// presignV4 adds the x-oss-* signature query parameters to req,
+// the url is valid for expires seconds from now
+func (api *OssApi) presignV4(req *request, creds *Credentials, now time.Time, expires int64) string {
+	if req.params == nil {
+		req.params = make(map[string][]string)
+	}
+	now = now.UTC()
+	additionalHeaders := api.presentAdditionalHeaders(req)
+	if creds.SecurityToken != "" {
+		req.params["x-oss-security-token"] = []string{creds.SecurityToken}
+	}
+	req.params["x-oss-signature-version"] = []string{signingAlgorithmV4}
+	req.params["x-oss-credential"] = []string{creds.AccessKeyId + "/" + api.scopeV4(now)}
+	req.params["x-oss-date"] = []string{now.Format(timeFormatV4)}
+	req.params["x-oss-expires"] = []string{strconv.FormatInt(expires, 10)}
+	if len(additionalHeaders) > 0 {
+		req.params["x-oss-additional-headers"] = []string{strings.Join(additionalHeaders, ";")}
+	}
+	signature := api.signatureV4(req, creds, now, additionalHeaders)
+	req.params["x-oss-signature"] = []string{signature}
+	return signature
+}
+
+func (api *OssApi) scopeV4(now time.Time) string {
+	return now.Format(shortTimeFormatV4) + "/" + api.signRegion() + "/" + signingProductV4 + "/" + signingTerminatorV4
+}
+
+func escapeV4(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+func (api *OssApi) signatureV4(req *request, creds *Credentials, now time.Time, additionalHeaders []string) string {
+	canonicalUri := "/" + api.bucket + "/" + strings.Replace(escapeV4(req.object), "%2F", "/", -1)
+
+	var queries []string
+	for k, v := range req.params {
+		for _, vi := range v {
+			if vi == "" {
+				queries = append(queries, escapeV4(k))
+			} else {
+				queries = append(queries, escapeV4(k)+"="+escapeV4(vi))
+			}
+		}
+	}
+	sort.Strings(queries)
+
+	var headers []string
+	hashedPayload := unsignedPayload
+	for k, v := range req.headers {
+		k = strings.ToLower(k)
+		signed := k == "content-type" || k == "content-md5" || strings.HasPrefix(k, "x-oss-")
+		for _, h := range additionalHeaders {
+			signed = signed || k == h
+		}
+		if signed {
+			headers = append(headers, k+":"+strings.TrimSpace(strings.Join(v, ","))+"\n")
+		}
+		if k == "x-oss-content-sha256" {
+			hashedPayload = v[0]
+		}
+	}
+	sort.Strings(headers)
+
+	canonicalRequest := req.method + "\n" +
+		canonicalUri + "\n" +
+		strings.Join(queries, "&") + "\n" +
+		strings.Join(headers, "") + "\n" +
+		strings.Join(additionalHeaders, ";") + "\n" +
+		hashedPayload
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	payload := signingAlgorithmV4 + "\n" +
+		now.Format(timeFormatV4) + "\n" +
+		api.scopeV4(now) + "\n" +
+		hex.EncodeToString(hashedRequest[:])
+
+	key := signingKeyV4(creds.AccessKeySecret, now, api.signRegion())
+	signature := hex.EncodeToString(hmacSHA256(key, payload))
+
+	api.logger.Debug("oss signature", "canonical_request", redactToken(canonicalRequest, creds), "payload", payload)
+	return signature
+}
+
+func signingKeyV4(accessKeySecret string, now time.Time, region string) []byte {
+	key := hmacSHA256([]byte("aliyun_v4"+accessKeySecret), now.UTC().Format(shortTimeFormatV4))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, signingProductV4)
+	return hmacSHA256(key, signingTerminatorV4)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	hash := hmac.New(sha256.New, key)
+	hash.Write([]byte(data))
+	return hash.Sum(nil)
+}
@@ -0,0 +1,232 @@
+package oss
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// test vectors of the official aliyun-oss-go-sdk
+
+func newSignV4TestRequest(contentType string) *request {
+	req := &request{
+		method: "PUT",
+		object: "1234+-/123/1.txt",
+		headers: http.Header{
+			"X-Oss-Head1":  {"value"},
+			"Abc":          {"value"},
+			"Zabc":         {"value"},
+			"Xyz":          {"value"},
+			"Content-Type": {contentType},
+		},
+		params: map[string][]string{
+			"param1":  {"value1"},
+			"+param1": {"value3"},
+			"|param1": {"value4"},
+			"+param2": {""},
+			"|param2": {""},
+			"param2":  {""},
+		},
+	}
+	return req
+}
+
+func TestSignV4Header(t *testing.T) {
+	cases := []struct {
+		signTime          int64
+		securityToken     string
+		additionalHeaders []string
+		authorization     string
+	}{
+		{
+			1702743657, "", nil,
+			"OSS4-HMAC-SHA256 Credential=ak/20231216/cn-hangzhou/oss/aliyun_v4_request,Signature=e21d18daa82167720f9b1047ae7e7f1ce7cb77a31e8203a7d5f4624fa0284afe",
+		},
+		{
+			1702784856, "token", nil,
+			"OSS4-HMAC-SHA256 Credential=ak/20231217/cn-hangzhou/oss/aliyun_v4_request,Signature=b94a3f999cf85bcdc00d332fbd3734ba03e48382c36fa4d5af5df817395bd9ea",
+		},
+		{
+			1702747512, "", []string{"ZAbc", "abc"},
+			"OSS4-HMAC-SHA256 Credential=ak/20231216/cn-hangzhou/oss/aliyun_v4_request,AdditionalHeaders=abc;zabc,Signature=4a4183c187c07c8947db7620deb0a6b38d9fbdd34187b6dbaccb316fa251212f",
+		},
+	}
+	for _, c := range cases {
+		client := NewWithSecurityToken("oss-cn-hangzhou", "ak", "sk", c.securityToken, "bucket", false)
+		client.SetSignatureVersion(SignatureV4, c.additionalHeaders...)
+		req := newSignV4TestRequest("text/plain")
+		signTime := time.Unix(c.signTime, 0)
+		req.headers["Date"] = []string{signTime.UTC().Format(http.TimeFormat)}
+		if c.securityToken != "" {
+			req.headers["x-oss-security-token"] = []string{c.securityToken}
+		}
+		creds, _ := client.credentials.get()
+		client.signV4(req, creds, signTime)
+		if auth := req.headers["Authorization"][0]; auth != c.authorization {
+			t.Errorf("wrong authorization expected %s, actual %s", c.authorization, auth)
+		}
+	}
+}
+
+func TestPresignV4(t *testing.T) {
+	cases := []struct {
+		signTime          int64
+		securityToken     string
+		additionalHeaders []string
+		signature         string
+	}{
+		{1702781677, "", nil, "a39966c61718be0d5b14e668088b3fa07601033f6518ac7b523100014269c0fe"},
+		{1702785388, "token", nil, "3817ac9d206cd6dfc90f1c09c00be45005602e55898f26f5ddb06d7892e1f8b5"},
+		{1702783809, "", []string{"ZAbc", "abc"}, "6bd984bfe531afb6db1f7550983a741b103a8c58e5e14f83ea474c2322dfa2b7"},
+	}
+	for _, c := range cases {
+		client := NewWithSecurityToken("oss-cn-hangzhou", "ak", "sk", c.securityToken, "bucket", false)
+		client.SetSignatureVersion(SignatureV4, c.additionalHeaders...)
+		req := newSignV4TestRequest("application/octet-stream")
+		creds, _ := client.credentials.get()
+		client.presignV4(req, creds, time.Unix(c.signTime, 0), 599)
+		if signature := req.params["x-oss-signature"][0]; signature != c.signature {
+			t.Errorf("wrong signature expected %s, actual %s", c.signature, signature)
+		}
+		if c.securityToken != "" && req.params["x-oss-security-token"][0] != c.securityToken {
+			t.Errorf("missing x-oss-security-token %v", req.params)
+		}
+	}
+}
+
+func TestSignRegion(t *testing.T) {
+	for region, expected := range map[string]string{
+		"oss-cn-hangzhou":          "cn-hangzhou",
+		"oss-cn-shenzhen-internal": "cn-shenzhen",
+		"ap-southeast-1":           "ap-southeast-1",
+	} {
+		client := New(region, "ak", "sk", "bucket", false)
+		if actual := client.signRegion(); actual != expected {
+			t.Errorf("wrong sign region expected %s, actual %s", expected, actual)
+		}
+	}
+}
+
+func TestPresignUrl(t *testing.T) {
+	client := NewWithSecurityToken("oss-cn-hangzhou", "ak", "sk", "token", "bucket", true)
+	expires := time.Unix(2000000000, 0)
+	cases := []struct {
+		method   string
+		options  []Option
+		resource string
+		payload  string
+	}{
+		{
+			"GET",
+			[]Option{ResponseContentDisposition("attachment; filename=a.txt"), Process("image/resize,w_100")},
+			"?response-content-disposition=attachment; filename=a.txt&security-token=token&x-oss-process=image/resize,w_100",
+			"GET\n\n\n2000000000\n",
+		},
+		{
+			"PUT",
+			[]Option{ContentType("text/plain"), WithHeader("x-oss-meta-author", "me")},
+			"?security-token=token",
+			"PUT\n\ntext/plain\n2000000000\nx-oss-meta-author:me\n",
+		},
+		{
+			"PUT",
+			[]Option{Part(&UploadContext{UploadId: "upload"}, 2)},
+			"?partNumber=2&security-token=token&uploadId=upload",
+			"PUT\n\n\n2000000000\n",
+		},
+	}
+	for _, c := range cases {
+		presigned, err := client.PresignUrl(c.method, "/dir/a b.txt", expires, c.options...)
+		if err != nil {
+			t.Fatalf("cant presign url %v", err)
+		}
+		u, err := url.Parse(presigned)
+		if err != nil {
+			t.Fatalf("cant parse presigned url %v", err)
+		}
+		if u.Scheme != "https" || u.Host != "bucket.oss-cn-hangzhou.aliyuncs.com" || u.Path != "/dir/a b.txt" {
+			t.Errorf("wrong presigned url %s", presigned)
+		}
+		query := u.Query()
+		if query.Get("Expires") != "2000000000" || query.Get("OSSAccessKeyId") != "ak" {
+			t.Errorf("wrong presigned url %s", presigned)
+		}
+
+		hash := hmac.New(sha1.New, []byte("sk"))
+		hash.Write([]byte(c.payload + "/bucket/dir/a b.txt" + c.resource))
+		if signature := base64.StdEncoding.EncodeToString(hash.Sum(nil)); query.Get("Signature") != signature {
+			t.Errorf("wrong signature of %s %s expected %s, actual %s", c.method, presigned, signature, query.Get("Signature"))
+		}
+	}
+}
+
+func TestPresignUrlFor(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	presigned, err := client.PresignUrlFor("HEAD", "object", time.Minute)
+	if err != nil {
+		t.Fatalf("cant presign url %v", err)
+	}
+	u, _ := url.Parse(presigned)
+	expires, _ := strconv.ParseInt(u.Query().Get("Expires"), 10, 64)
+	if delta := expires - time.Now().Add(time.Minute).Unix(); delta < -5 || delta > 5 {
+		t.Errorf("wrong Expires %d", expires)
+	}
+
+	client.SetSignatureVersion(SignatureV4)
+	presigned, err = client.PresignUrlFor("DELETE", "object", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("cant presign url %v", err)
+	}
+	u, _ = url.Parse(presigned)
+	if seconds := u.Query().Get("x-oss-expires"); seconds != "600" && seconds != "599" {
+		t.Errorf("wrong x-oss-expires %s", seconds)
+	}
+	if u.Query().Get("x-oss-signature") == "" {
+		t.Errorf("missing x-oss-signature %s", presigned)
+	}
+}
+
+func TestSignV4Fake(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.Region = "cn-hangzhou"
+	client.SetSignatureVersion(SignatureV4, "host")
+	key := "dir/a b+c.txt"
+	if err := client.PutObject(key, []byte("hello"), "text/plain", WithHeader("x-oss-meta-owner", "x")); err != nil {
+		t.Fatalf("the v4 put should be accepted %v", err)
+	}
+	if data, err := client.GetObject(key); err != nil || string(data) != "hello" {
+		t.Errorf("the v4 get should be accepted %s %v", data, err)
+	}
+	presigned, err := client.PresignUrlFor("GET", key, time.Minute)
+	if err != nil {
+		t.Fatalf("cant presign url %v", err)
+	}
+	resp, err := http.Get(presigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("the v4 presigned url should be accepted %d", resp.StatusCode)
+	}
+
+	other := New("oss-cn-shanghai", "ak", "sk", "bucket", false)
+	other.SetEndpoint(server.URL)
+	other.SetSignatureVersion(SignatureV4)
+	var ossErr *Error
+	if _, err := other.GetObject(key); !errors.As(err, &ossErr) || ossErr.Code != "SignatureDoesNotMatch" {
+		t.Errorf("the wrong region should be rejected %v", err)
+	}
+	other = New("oss-cn-hangzhou", "ak", "bad", "bucket", false)
+	other.SetEndpoint(server.URL)
+	other.SetSignatureVersion(SignatureV4)
+	if _, err := other.GetObject(key); !errors.As(err, &ossErr) || ossErr.Code != "SignatureDoesNotMatch" {
+		t.Errorf("the wrong secret should be rejected %v", err)
+	}
+}
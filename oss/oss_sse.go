@@ -0,0 +1,87 @@
+package oss
+
+import (
+	"crypto/md5"
+	"strings"
+)
+
+// the x-oss-server-side-encryption algorithms
+const (
+	SSEAES256 = "AES256"
+	SSEKMS    = "KMS"
+	SSESM4    = "SM4"
+)
+
+// ServerSideEncryption encrypts the object with the keys managed by oss,
+// algorithm is SSEAES256 or SSESM4
+func ServerSideEncryption(algorithm string) Option {
+	return WithHeader("x-oss-server-side-encryption", algorithm)
+}
+
+// ServerSideEncryptionKMS encrypts the object with the KMS key keyId,
+// the default KMS key of the account if empty, dataEncryption is "" for AES256 or SSESM4
+func ServerSideEncryptionKMS(keyId, dataEncryption string) Option {
+	return func(req *request) {
+		req.setHeader("x-oss-server-side-encryption", SSEKMS)
+		if keyId != "" {
+			req.setHeader("x-oss-server-side-encryption-key-id", keyId)
+		}
+		if dataEncryption != "" {
+			req.setHeader("x-oss-server-side-data-encryption", dataEncryption)
+		}
+	}
+}
+
+const (
+	sseCustomerPrefix           = "x-oss-server-side-encryption-customer-"
+	copySourceSSECustomerPrefix = "x-oss-copy-source-server-side-encryption-customer-"
+)
+
+// SSECustomerKey encrypts the object with the 32 bytes AES256 key of the customer,
+// the same key must be sent with every part and every GET or HEAD of the object
+func SSECustomerKey(key []byte) Option {
+	return customerKey(sseCustomerPrefix, key)
+}
+
+// CopySourceSSECustomerKey decrypts the source of a copy encrypted with SSECustomerKey(key)
+func CopySourceSSECustomerKey(key []byte) Option {
+	return customerKey(copySourceSSECustomerPrefix, key)
+}
+
+func customerKey(prefix string, key []byte) Option {
+	sum := md5.Sum(key)
+	return func(req *request) {
+		req.setHeader(prefix+"algorithm", SSEAES256)
+		req.setHeader(prefix+"key", b64.EncodeToString(key))
+		req.setHeader(prefix+"key-md5", b64.EncodeToString(sum[:]))
+	}
+}
+
+// copyOptions splits the options of Copy into the options of InitMultipartUpload,
+// the source version and the customer keys repeated by every UploadPartCopy
+// and the ForbidOverwrite repeated by CompleteMultipart
+func copyOptions(options []Option) ([]Option, []Option, []Option) {
+	req := &request{}
+	req.apply(options)
+	var partOptions, completeOptions []Option
+	for key, values := range req.headers {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, sseCustomerPrefix) || strings.HasPrefix(lower, copySourceSSECustomerPrefix) {
+			partOptions = append(partOptions, WithHeader(key, values[0]))
+		}
+		if lower == "x-oss-forbid-overwrite" {
+			completeOptions = append(completeOptions, WithHeader(key, values[0]))
+		}
+	}
+	if req.copySourceVersionId != "" {
+		partOptions = append(partOptions, CopySourceVersionId(req.copySourceVersionId))
+	}
+	initOptions := append(options[:len(options):len(options)], Progress(nil), func(req *request) {
+		for key := range req.headers {
+			if strings.HasPrefix(strings.ToLower(key), copySourceSSECustomerPrefix) {
+				delete(req.headers, key)
+			}
+		}
+	})
+	return initOptions, partOptions, completeOptions
+}
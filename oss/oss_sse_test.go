@@ -0,0 +1,96 @@
+package oss
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServerSideEncryptionOptions(t *testing.T) {
+	req := &request{}
+	req.apply([]Option{ServerSideEncryptionKMS("key", SSESM4)})
+	if req.headers["x-oss-server-side-encryption"][0] != "KMS" ||
+		req.headers["x-oss-server-side-encryption-key-id"][0] != "key" ||
+		req.headers["x-oss-server-side-data-encryption"][0] != "SM4" {
+		t.Errorf("wrong kms headers %v", req.headers)
+	}
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	sum := md5.Sum(key)
+	req = &request{}
+	req.apply([]Option{ServerSideEncryption(SSEAES256), SSECustomerKey(key)})
+	if req.headers["x-oss-server-side-encryption"][0] != "AES256" ||
+		req.headers["x-oss-server-side-encryption-customer-algorithm"][0] != "AES256" ||
+		req.headers["x-oss-server-side-encryption-customer-key"][0] != base64.StdEncoding.EncodeToString(key) ||
+		req.headers["x-oss-server-side-encryption-customer-key-md5"][0] != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Errorf("wrong customer key headers %v", req.headers)
+	}
+}
+
+func TestCopyWithCustomerKeys(t *testing.T) {
+	source := []byte("0123456789abcdef0123456789abcdef")
+	target := []byte("fedcba9876543210fedcba9876543210")
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	headers := map[string]http.Header{}
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		query := r.URL.Query()
+		if _, ok := query["uploads"]; ok {
+			headers["init"] = r.Header
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>")),
+			}
+		}
+		if query.Get("partNumber") != "" {
+			headers["part"] = r.Header
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Range": {"bytes 0-9/10"}},
+				Body:       ioutil.NopCloser(strings.NewReader("<CopyPartResult><ETag>etag</ETag></CopyPartResult>")),
+			}
+		}
+		return &http.Response{StatusCode: http.StatusOK}
+	}))
+	err := client.Copy("", "a.txt", "b.txt", "text/plain", 0,
+		SSECustomerKey(target), CopySourceSSECustomerKey(source), WithHeader("x-oss-meta-owner", "me"))
+	if err != nil {
+		t.Fatalf("cant copy %v", err)
+	}
+	targetKey := base64.StdEncoding.EncodeToString(target)
+	sourceKey := base64.StdEncoding.EncodeToString(source)
+	if headers["init"]["x-oss-server-side-encryption-customer-key"][0] != targetKey ||
+		headers["init"]["x-oss-meta-owner"][0] != "me" {
+		t.Errorf("wrong init headers %v", headers["init"])
+	}
+	if _, ok := headers["init"]["x-oss-copy-source-server-side-encryption-customer-key"]; ok {
+		t.Errorf("the source key should only be sent with the parts %v", headers["init"])
+	}
+	if headers["part"]["x-oss-server-side-encryption-customer-key"][0] != targetKey ||
+		headers["part"]["x-oss-copy-source-server-side-encryption-customer-key"][0] != sourceKey {
+		t.Errorf("wrong part headers %v", headers["part"])
+	}
+	if _, ok := headers["part"]["x-oss-meta-owner"]; ok {
+		t.Errorf("the metadata should only be sent with the init %v", headers["part"])
+	}
+}
+
+func TestCopyOptionsIgnoreHeaderCase(t *testing.T) {
+	initOptions, partOptions, _ := copyOptions([]Option{
+		WithHeader("X-Oss-Server-Side-Encryption-Customer-Key", "target"),
+		WithHeader("X-Oss-Copy-Source-Server-Side-Encryption-Customer-Key", "source"),
+	})
+	part := &request{}
+	part.apply(partOptions)
+	if part.headers["X-Oss-Server-Side-Encryption-Customer-Key"][0] != "target" ||
+		part.headers["X-Oss-Copy-Source-Server-Side-Encryption-Customer-Key"][0] != "source" {
+		t.Errorf("the customer keys should be sent with the parts %v", part.headers)
+	}
+	init := &request{}
+	init.apply(initOptions)
+	if _, ok := init.headers["X-Oss-Copy-Source-Server-Side-Encryption-Customer-Key"]; ok || len(init.headers) != 1 {
+		t.Errorf("the source key should only be sent with the parts %v", init.headers)
+	}
+}
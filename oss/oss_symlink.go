@@ -0,0 +1,128 @@
+package oss
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// the x-oss-object-type of the objects
+const (
+	ObjectTypeNormal     = "Normal"
+	ObjectTypeMultipart  = "Multipart"
+	ObjectTypeAppendable = "Appendable"
+	ObjectTypeSymlink    = "Symlink"
+)
+
+// PutSymlink creates the symlink pointing to target, the target doesn't need to exist.
+// GetObject and GetObjectMetadata of the symlink return the target
+func (api *OssApi) PutSymlink(symlink, target string, options ...Option) error {
+	req := &request{
+		operation: "PutSymlink",
+		method:    "PUT",
+		object:    noramilizeObject(symlink),
+		params: map[string][]string{
+			"symlink": {""},
+		},
+		headers: map[string][]string{
+			"x-oss-symlink-target": {url.QueryEscape(noramilizeObject(target))},
+		},
+	}
+	req.apply(options)
+	return api.query(req, nil)
+}
+
+// GetSymlink returns the target of the symlink
+func (api *OssApi) GetSymlink(symlink string, options ...Option) (string, error) {
+	req := &request{
+		operation: "GetSymlink",
+		method:    "GET",
+		object:    noramilizeObject(symlink),
+		params: map[string][]string{
+			"symlink": {""},
+		},
+	}
+	req.apply(options)
+	hresp, err := api.rawQuery(req)
+	if err != nil {
+		return "", err
+	}
+	hresp.Body.Close()
+	return url.QueryUnescape(hresp.Header.Get("x-oss-symlink-target"))
+}
+
+// GetObjectType returns the x-oss-object-type, e.g. ObjectTypeSymlink
+func (header *Header) GetObjectType() string {
+	return header.Get("x-oss-object-type")
+}
+
+func (header *Header) IsSymlink() bool {
+	return header.GetObjectType() == ObjectTypeSymlink
+}
+
+// ResolveSymlink returns the object the metadata of object come from,
+// i.e. the target if object is a symlink or object itself otherwise
+func (api *OssApi) ResolveSymlink(object string, options ...Option) (string, *Header, error) {
+	header, err := api.GetObjectMetadata(object, options...)
+	if err != nil {
+		return "", nil, err
+	}
+	if !header.IsSymlink() {
+		return noramilizeObject(object), header, nil
+	}
+	target, err := api.GetSymlink(object, options...)
+	if err != nil {
+		return "", nil, err
+	}
+	return target, header, nil
+}
+
+type ObjectProperties struct {
+	Key          string
+	Type         string
+	Size         int64
+	ETag         string
+	LastModified string
+	StorageClass StorageClass
+}
+
+func (object *ObjectProperties) IsSymlink() bool {
+	return object.Type == ObjectTypeSymlink
+}
+
+type ListObjectsResult struct {
+	Objects        []ObjectProperties `xml:"Contents"`
+	CommonPrefixes []string           `xml:"CommonPrefixes>Prefix"`
+	IsTruncated    bool
+	NextMarker     string
+}
+
+// ListObjects is ListFiles returning the properties of the objects,
+// pass the NextMarker of the result to get the next page
+func (api *OssApi) ListObjects(prefix, delimiter, marker string, max int) (*ListObjectsResult, error) {
+	params := make(map[string][]string)
+	if prefix = noramilizeObject(prefix); prefix != "" {
+		params["prefix"] = []string{prefix}
+	}
+	if delimiter != "" {
+		params["delimiter"] = []string{delimiter}
+	}
+	if marker != "" {
+		params["marker"] = []string{marker}
+	}
+	if max > 0 && max < 1000 {
+		params["max-keys"] = []string{strconv.Itoa(max)}
+	}
+	req := &request{
+		operation: "ListObjects",
+		method:    "GET",
+		params:    params,
+	}
+	var resp ListObjectsResult
+	if err := api.query(req, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.IsTruncated {
+		resp.NextMarker = ""
+	}
+	return &resp, nil
+}
@@ -0,0 +1,79 @@
+package oss
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSymlink(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	logger := &recordingLogger{}
+	client.SetLogger(logger)
+	var target string
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		switch {
+		case r.Method == "PUT":
+			target = r.Header["x-oss-symlink-target"][0]
+			return &http.Response{StatusCode: http.StatusOK}
+		case r.Method == "HEAD" && r.URL.Path == "/latest":
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Oss-Object-Type": {"Symlink"}}}
+		case r.Method == "HEAD":
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Oss-Object-Type": {"Normal"}}}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Oss-Symlink-Target": {target}}}
+	}))
+	if err := client.PutSymlink("latest", "/releases/v 1.0&rc"); err != nil {
+		t.Fatalf("cant put symlink %v", err)
+	}
+	if target != "releases%2Fv+1.0%26rc" {
+		t.Errorf("the target should be escaped %s", target)
+	}
+	if !strings.Contains(strings.Join(logger.lines, "\n"), "/bucket/latest?symlink") {
+		t.Errorf("the symlink should be signed as a sub resource %v", logger.lines)
+	}
+	received, err := client.GetSymlink("latest")
+	if err != nil || received != "releases/v 1.0&rc" {
+		t.Errorf("wrong target %s %v", received, err)
+	}
+	resolved, header, err := client.ResolveSymlink("latest")
+	if err != nil || resolved != "releases/v 1.0&rc" || !header.IsSymlink() {
+		t.Errorf("wrong resolved symlink %s %v", resolved, err)
+	}
+	resolved, header, err = client.ResolveSymlink("/plain")
+	if err != nil || resolved != "plain" || header.IsSymlink() {
+		t.Errorf("wrong resolved object %s %v", resolved, err)
+	}
+}
+
+func TestListObjectsXML(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var query string
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		query = r.URL.RawQuery
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`<ListBucketResult>
+  <IsTruncated>true</IsTruncated>
+  <NextMarker>logs/b</NextMarker>
+  <Contents><Key>logs/a</Key><Type>Normal</Type><Size>10</Size><ETag>"etag"</ETag><StorageClass>Archive</StorageClass></Contents>
+  <Contents><Key>logs/latest</Key><Type>Symlink</Type><Size>0</Size></Contents>
+  <CommonPrefixes><Prefix>logs/2024/</Prefix></CommonPrefixes>
+</ListBucketResult>`))}
+	}))
+	result, err := client.ListObjects("/logs/", "/", "", 2)
+	if err != nil {
+		t.Fatalf("cant list objects %v", err)
+	}
+	if query != "delimiter=%2F&max-keys=2&prefix=logs%2F" {
+		t.Errorf("wrong query %s", query)
+	}
+	if len(result.Objects) != 2 || result.NextMarker != "logs/b" || len(result.CommonPrefixes) != 1 {
+		t.Fatalf("wrong result %+v", result)
+	}
+	if result.Objects[0].IsSymlink() || result.Objects[0].Size != 10 || result.Objects[0].StorageClass != StorageArchive {
+		t.Errorf("wrong object %+v", result.Objects[0])
+	}
+	if !result.Objects[1].IsSymlink() {
+		t.Errorf("the symlink should be flagged %+v", result.Objects[1])
+	}
+}
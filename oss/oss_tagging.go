@@ -0,0 +1,115 @@
+package oss
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+)
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Tags    []Tag    `xml:"TagSet>Tag"`
+}
+
+// encodeTags returns the x-oss-tagging value of tags, keeping their order
+func encodeTags(tags []Tag) string {
+	pairs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		pairs = append(pairs, url.QueryEscape(tag.Key)+"="+url.QueryEscape(tag.Value))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Tagging sets the tags of the object uploaded by PutObject, InitMultipartUpload,
+// Copy or CopyObject, an object has up to 10 tags
+func Tagging(tags ...Tag) Option {
+	return WithHeader("x-oss-tagging", encodeTags(tags))
+}
+
+// the x-oss-tagging-directive and x-oss-metadata-directive of CopyObject
+const (
+	DirectiveCopy    = "COPY"
+	DirectiveReplace = "REPLACE"
+)
+
+// TaggingDirective tells CopyObject to copy the tags of the source or replace them
+// with the Tagging option
+func TaggingDirective(directive string) Option {
+	return WithHeader("x-oss-tagging-directive", directive)
+}
+
+// MetadataDirective tells CopyObject to copy the metadata of the source or replace them
+// with the metadata of the request
+func MetadataDirective(directive string) Option {
+	return WithHeader("x-oss-metadata-directive", directive)
+}
+
+// CopyObject copies the source object up to 1GB in a single request,
+// the tags and the metadata are copied unless the directives replace them
+func (api *OssApi) CopyObject(sourceBucket, sourceObject, target string, options ...Option) error {
+	sourceObject = noramilizeObject(sourceObject)
+	if sourceBucket == "" {
+		sourceBucket = api.bucket
+	}
+	req := &request{
+		operation: "CopyObject",
+		method:    "PUT",
+		object:    noramilizeObject(target),
+		headers: map[string][]string{
+			"x-oss-copy-source": {"/" + sourceBucket + "/" + sourceObject},
+		},
+	}
+	req.apply(options)
+	if req.copySourceVersionId != "" {
+		req.headers["x-oss-copy-source"][0] += "?versionId=" + req.copySourceVersionId
+	}
+	return api.query(req, nil)
+}
+
+func (api *OssApi) PutObjectTagging(object string, tags []Tag, options ...Option) error {
+	data, err := xml.Marshal(&tagging{Tags: tags})
+	if err != nil {
+		return err
+	}
+	req := &request{
+		operation: "PutObjectTagging",
+		method:    "PUT",
+		object:    noramilizeObject(object),
+		params: map[string][]string{
+			"tagging": {""},
+		},
+		payload: data,
+	}
+	req.apply(options)
+	return api.query(req, nil)
+}
+
+func (api *OssApi) GetObjectTagging(object string, options ...Option) ([]Tag, error) {
+	req := &request{
+		operation: "GetObjectTagging",
+		method:    "GET",
+		object:    noramilizeObject(object),
+		params: map[string][]string{
+			"tagging": {""},
+		},
+	}
+	req.apply(options)
+	var resp tagging
+	if err := api.query(req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+func (api *OssApi) DeleteObjectTagging(object string, options ...Option) error {
+	req := &request{
+		operation: "DeleteObjectTagging",
+		method:    "DELETE",
+		object:    noramilizeObject(object),
+		params: map[string][]string{
+			"tagging": {""},
+		},
+	}
+	req.apply(options)
+	return api.query(req, nil)
+}
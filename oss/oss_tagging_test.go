@@ -0,0 +1,76 @@
+package oss
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObjectTagging(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	logger := &recordingLogger{}
+	client.SetLogger(logger)
+	var sent []byte
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		if r.Method == "PUT" {
+			sent = body
+		}
+		if r.Method == "GET" {
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(
+				`<Tagging><TagSet><Tag><Key>team</Key><Value>infra</Value></Tag><Tag><Key>cost</Key><Value>a&amp;b</Value></Tag></TagSet></Tagging>`))}
+		}
+		return &http.Response{StatusCode: http.StatusOK}
+	}))
+	tags := []Tag{{"team", "infra"}, {"cost", "a&b"}}
+	if err := client.PutObjectTagging("a.txt", tags, VersionId("v1")); err != nil {
+		t.Fatalf("cant put tagging %v", err)
+	}
+	expected := "<Tagging><TagSet><Tag><Key>team</Key><Value>infra</Value></Tag><Tag><Key>cost</Key><Value>a&amp;b</Value></Tag></TagSet></Tagging>"
+	if string(sent) != expected {
+		t.Errorf("wrong tagging xml expected %s, actual %s", expected, sent)
+	}
+	if !strings.Contains(strings.Join(logger.lines, "\n"), "/bucket/a.txt?tagging&versionId=v1") {
+		t.Errorf("the tagging should be signed as a sub resource %v", logger.lines)
+	}
+	received, err := client.GetObjectTagging("a.txt")
+	if err != nil || !reflect.DeepEqual(received, tags) {
+		t.Errorf("wrong tags %v %v", received, err)
+	}
+	if err := client.DeleteObjectTagging("a.txt"); err != nil {
+		t.Errorf("cant delete tagging %v", err)
+	}
+}
+
+func TestTaggingOnUpload(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var headers []http.Header
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		headers = append(headers, r.Header)
+		if len(r.URL.Query()["uploads"]) > 0 {
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>"))}
+		}
+		return &http.Response{StatusCode: http.StatusOK}
+	}))
+	tagging := Tagging(Tag{"team", "infra"}, Tag{"owner", "a b"})
+	if err := client.PutObject("a.txt", []byte("hello"), "text/plain", tagging); err != nil {
+		t.Fatalf("cant put object %v", err)
+	}
+	if _, err := client.InitMultipartUpload("b.txt", "text/plain", tagging); err != nil {
+		t.Fatalf("cant init upload %v", err)
+	}
+	if err := client.CopyObject("", "a.txt", "c.txt", tagging, TaggingDirective(DirectiveReplace), CopySourceVersionId("v1")); err != nil {
+		t.Fatalf("cant copy object %v", err)
+	}
+	for _, header := range headers {
+		values, err := url.ParseQuery(header["x-oss-tagging"][0])
+		if err != nil || values.Get("team") != "infra" || values.Get("owner") != "a b" {
+			t.Errorf("wrong tagging header %v", header["x-oss-tagging"])
+		}
+	}
+	if headers[2]["x-oss-tagging-directive"][0] != "REPLACE" || headers[2]["x-oss-copy-source"][0] != "/bucket/a.txt?versionId=v1" {
+		t.Errorf("wrong copy headers %v", headers[2])
+	}
+}
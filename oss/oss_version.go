@@ -0,0 +1,174 @@
+package oss
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// the status of the bucket versioning, a bucket never versioned has an empty status
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+)
+
+type versioningConfiguration struct {
+	XMLName xml.Name `xml:"VersioningConfiguration"`
+	Status  string   `xml:"Status,omitempty"`
+}
+
+// PutBucketVersioning sets the status to VersioningEnabled or VersioningSuspended,
+// the versioning of a bucket can't be turned off once enabled
+func (api *OssApi) PutBucketVersioning(status string) error {
+	return api.putBucketConfig("versioning", &versioningConfiguration{Status: status})
+}
+
+func (api *OssApi) GetBucketVersioning() (string, error) {
+	var config versioningConfiguration
+	err := api.getBucketConfig("versioning", &config)
+	if err != nil {
+		return "", err
+	}
+	return config.Status, nil
+}
+
+// VersionId addresses a version of the object in GetObjectRange, GetObjectMetadata,
+// DeleteObject and the tagging and symlink apis
+func VersionId(versionId string) Option {
+	return WithParam("versionId", versionId)
+}
+
+// CopySourceVersionId copies a version of the source object in Copy and UploadCopyMultipart
+func CopySourceVersionId(versionId string) Option {
+	return func(req *request) {
+		req.copySourceVersionId = versionId
+	}
+}
+
+// GetVersionId returns the x-oss-version-id of the object, "null" if it was
+// written while the versioning was not enabled
+func (header *Header) GetVersionId() string {
+	return header.Get("x-oss-version-id")
+}
+
+// DeleteObject returns the version id of the delete marker created in a versioned bucket,
+// or the deleted version with the VersionId option
+func (api *OssApi) DeleteObject(object string, options ...Option) (string, error) {
+	object = noramilizeObject(object)
+	req := &request{
+		operation: "DeleteObject",
+		method:    "DELETE",
+		object:    object,
+	}
+	req.apply(options)
+	hresp, err := api.rawQuery(req)
+	if err != nil {
+		return "", err
+	}
+	hresp.Body.Close()
+	return hresp.Header.Get("x-oss-version-id"), nil
+}
+
+type ObjectVersion struct {
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass StorageClass
+}
+
+type DeleteMarker struct {
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified string
+}
+
+type ListObjectVersionsResult struct {
+	Versions            []ObjectVersion `xml:"Version"`
+	DeleteMarkers       []DeleteMarker  `xml:"DeleteMarker"`
+	CommonPrefixes      []string        `xml:"CommonPrefixes>Prefix"`
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIdMarker string
+}
+
+// ListObjectVersions lists the versions and the delete markers of the objects after
+// keyMarker and versionIdMarker, pass the Next markers of the result to get the next page
+func (api *OssApi) ListObjectVersions(prefix, delimiter, keyMarker, versionIdMarker string, max int) (*ListObjectVersionsResult, error) {
+	params := map[string][]string{
+		"versions": {""},
+	}
+	if prefix = noramilizeObject(prefix); prefix != "" {
+		params["prefix"] = []string{prefix}
+	}
+	if delimiter != "" {
+		params["delimiter"] = []string{delimiter}
+	}
+	if keyMarker != "" {
+		params["key-marker"] = []string{keyMarker}
+	}
+	if versionIdMarker != "" {
+		params["version-id-marker"] = []string{versionIdMarker}
+	}
+	if max > 0 && max < 1000 {
+		params["max-keys"] = []string{strconv.Itoa(max)}
+	}
+	req := &request{
+		operation: "ListObjectVersions",
+		method:    "GET",
+		params:    params,
+	}
+	var resp ListObjectVersionsResult
+	if err := api.query(req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ObjectVersionId is a version or a delete marker of an object,
+// an empty VersionId deletes the object like DeleteObject
+type ObjectVersionId struct {
+	Key       string
+	VersionId string `xml:"VersionId,omitempty"`
+}
+
+type DeletedVersion struct {
+	Key                   string
+	VersionId             string
+	DeleteMarker          bool   // a delete marker was deleted or created
+	DeleteMarkerVersionId string // the version id of the created or deleted delete marker
+}
+
+// DeleteVersions deletes the versions and the delete markers in one request
+func (api *OssApi) DeleteVersions(objects ...ObjectVersionId) ([]DeletedVersion, error) {
+	var multipleDelete struct {
+		XMLName xml.Name          `xml:"Delete"`
+		Quiet   bool              `xml:"Quiet"`
+		Objects []ObjectVersionId `xml:"Object"`
+	}
+	for _, object := range objects {
+		object.Key = noramilizeObject(object.Key)
+		multipleDelete.Objects = append(multipleDelete.Objects, object)
+	}
+	data, err := xml.Marshal(&multipleDelete)
+	if err != nil {
+		return nil, err
+	}
+	req := &request{
+		operation: "DeleteMultipleObjects",
+		method:    "POST",
+		params: map[string][]string{
+			"delete": {""},
+		},
+		payload: data,
+	}
+	var resp struct {
+		Deleted []DeletedVersion
+	}
+	if err := api.query(req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Deleted, nil
+}
@@ -0,0 +1,126 @@
+package oss
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVersioningConfigurationXML(t *testing.T) {
+	data, err := xml.Marshal(&versioningConfiguration{Status: VersioningEnabled})
+	if err != nil {
+		t.Fatalf("cant marshal versioning configuration %v", err)
+	}
+	if expected := "<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>"; string(data) != expected {
+		t.Errorf("wrong versioning xml expected %s, actual %s", expected, data)
+	}
+}
+
+func TestListObjectVersions(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var query string
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		query = r.URL.RawQuery
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<ListVersionsResult>
+  <Name>bucket</Name>
+  <Prefix>logs/</Prefix>
+  <KeyMarker></KeyMarker>
+  <VersionIdMarker></VersionIdMarker>
+  <MaxKeys>2</MaxKeys>
+  <IsTruncated>true</IsTruncated>
+  <NextKeyMarker>logs/b.txt</NextKeyMarker>
+  <NextVersionIdMarker>CAEQMxiBgICbof2D0BYiIGRhZjgwMzJiMjA3MjQ0ODE5MWYxZDYwMzJlZjU1****</NextVersionIdMarker>
+  <DeleteMarker>
+    <Key>logs/a.txt</Key>
+    <VersionId>CAEQMxiBgIDh3ZCB0BYiIGE4YjIyMjExZDhhYjQxNzZiNGUyZTI4ZjljZDgz****</VersionId>
+    <IsLatest>true</IsLatest>
+    <LastModified>2019-04-09T07:27:28.000Z</LastModified>
+  </DeleteMarker>
+  <Version>
+    <Key>logs/a.txt</Key>
+    <VersionId>CAEQMxiBgMDNoP2D0BYiIDE3MWUxNzgxZDQxNTRiODI5OGYwZGMwNGY3MzZjN****</VersionId>
+    <IsLatest>false</IsLatest>
+    <LastModified>2019-04-09T07:27:28.000Z</LastModified>
+    <ETag>"250F8A0AE989679A22926A875F0A2****"</ETag>
+    <Size>93731</Size>
+    <StorageClass>Standard</StorageClass>
+  </Version>
+  <CommonPrefixes><Prefix>logs/2019/</Prefix></CommonPrefixes>
+</ListVersionsResult>`))}
+	}))
+	result, err := client.ListObjectVersions("logs/", "/", "", "", 2)
+	if err != nil {
+		t.Fatalf("cant list versions %v", err)
+	}
+	if query != "delimiter=%2F&max-keys=2&prefix=logs%2F&versions=" {
+		t.Errorf("wrong query %s", query)
+	}
+	if len(result.Versions) != 1 || result.Versions[0].Size != 93731 || result.Versions[0].IsLatest || result.Versions[0].StorageClass != StorageStandard {
+		t.Errorf("wrong versions %#v", result.Versions)
+	}
+	if len(result.DeleteMarkers) != 1 || !result.DeleteMarkers[0].IsLatest {
+		t.Errorf("wrong delete markers %#v", result.DeleteMarkers)
+	}
+	if !result.IsTruncated || result.NextKeyMarker != "logs/b.txt" || len(result.CommonPrefixes) != 1 {
+		t.Errorf("wrong result %#v", result)
+	}
+}
+
+func TestDeleteVersions(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	var sent string
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		sent = string(body)
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`<DeleteResult>
+  <Deleted><Key>a.txt</Key><VersionId>v1</VersionId></Deleted>
+  <Deleted><Key>b.txt</Key><DeleteMarker>true</DeleteMarker><DeleteMarkerVersionId>m1</DeleteMarkerVersionId></Deleted>
+</DeleteResult>`))}
+	}))
+	deleted, err := client.DeleteVersions(ObjectVersionId{"/a.txt", "v1"}, ObjectVersionId{Key: "b.txt"})
+	if err != nil {
+		t.Fatalf("cant delete versions %v", err)
+	}
+	expected := "<Delete><Quiet>false</Quiet><Object><Key>a.txt</Key><VersionId>v1</VersionId></Object><Object><Key>b.txt</Key></Object></Delete>"
+	if sent != expected {
+		t.Errorf("wrong delete xml expected %s, actual %s", expected, sent)
+	}
+	if len(deleted) != 2 || deleted[0].VersionId != "v1" || !deleted[1].DeleteMarker || deleted[1].DeleteMarkerVersionId != "m1" {
+		t.Errorf("wrong deleted versions %#v", deleted)
+	}
+}
+
+func TestVersionIdOptions(t *testing.T) {
+	client := New("oss-cn-hangzhou", "ak", "sk", "bucket", false)
+	logger := &recordingLogger{}
+	client.SetLogger(logger)
+	var copySources []string
+	client.Use(serve(func(r *http.Request, body []byte) *http.Response {
+		query := r.URL.Query()
+		switch {
+		case r.Method == "DELETE":
+			return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{"X-Oss-Version-Id": {query.Get("versionId")}}}
+		case len(query["uploads"]) > 0:
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>"))}
+		case query.Get("partNumber") != "":
+			copySources = append(copySources, r.Header["x-oss-copy-source"][0])
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("<CopyPartResult><ETag>etag</ETag></CopyPartResult>"))}
+		}
+		return &http.Response{StatusCode: http.StatusOK}
+	}))
+	versionId, err := client.DeleteObject("a.txt", VersionId("v1"))
+	if err != nil || versionId != "v1" {
+		t.Errorf("cant delete the version %s %v", versionId, err)
+	}
+	if !strings.Contains(strings.Join(logger.lines, "\n"), "/bucket/a.txt?versionId=v1") {
+		t.Errorf("the version id should be signed as a sub resource %v", logger.lines)
+	}
+	if err := client.Copy("", "a.txt", "b.txt", "text/plain", 0, CopySourceVersionId("v2")); err != nil {
+		t.Fatalf("cant copy %v", err)
+	}
+	if len(copySources) != 1 || copySources[0] != "/bucket/a.txt?versionId=v2" {
+		t.Errorf("wrong copy source %v", copySources)
+	}
+}
@@ -0,0 +1,137 @@
+package ossfake
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// Fault makes the server misbehave on the matching requests.
+// The empty filters match any request
+type Fault struct {
+	Method      string
+	Key         string                     // the object key, "" matches the bucket requests too
+	SubResource string                     // a query parameter of the request, e.g. "uploadId"
+	Match       func(r *http.Request) bool // an extra filter
+	Times       int                        // the number of requests to fail, 0 for every request
+
+	Delay time.Duration // waits before serving the request
+
+	// Status and Code respond the error document instead of serving the request,
+	// e.g. 503 and "ServiceUnavailable"
+	Status  int
+	Code    string
+	Message string
+
+	// Corrupt flips a byte of the request body before it's served, the server detects it
+	// with the Content-MD5 if any, or a byte of the response body if the request has no body
+	Corrupt bool
+
+	// Drop closes the connection after the headers and DropAfter bytes of the body,
+	// a negative DropAfter closes it before the response
+	Drop      bool
+	DropAfter int
+
+	hits int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	if f.Times > 0 && f.hits >= f.Times {
+		return false
+	}
+	if f.Method != "" && f.Method != r.Method {
+		return false
+	}
+	if f.Key != "" && f.Key != strings.TrimPrefix(r.URL.Path, "/") {
+		return false
+	}
+	if f.SubResource != "" {
+		if _, ok := r.URL.Query()[f.SubResource]; !ok {
+			return false
+		}
+	}
+	return f.Match == nil || f.Match(r)
+}
+
+// Inject adds the fault, the first matching fault applies to a request
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes the injected faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetClockSkew moves the server clock by d, the requests are rejected
+// with RequestTimeTooSkewed once d exceeds 15 minutes
+func (s *Server) SetClockSkew(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = func() time.Time {
+		return time.Now().Add(d)
+	}
+}
+
+func (s *Server) fault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.faults {
+		if f.matches(r) {
+			f.hits++
+			return f
+		}
+	}
+	return nil
+}
+
+func corrupt(data []byte) {
+	if len(data) > 0 {
+		data[len(data)/2] ^= 0xff
+	}
+}
+
+// deliver writes the recorded response, or a part of it if the fault drops the connection
+func deliver(w http.ResponseWriter, rec *httptest.ResponseRecorder, f *Fault) {
+	body := rec.Body.Bytes()
+	if f == nil || !f.Drop {
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(body)
+		return
+	}
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if f.DropAfter < 0 {
+		return
+	}
+	writeHead(rw.Writer, rec)
+	if f.DropAfter < len(body) {
+		body = body[:f.DropAfter]
+	}
+	rw.Write(body)
+	rw.Flush()
+}
+
+func writeHead(w *bufio.Writer, rec *httptest.ResponseRecorder) {
+	fmt.Fprintf(w, "HTTP/1.1 %d %s\r\n", rec.Code, http.StatusText(rec.Code))
+	header := rec.Header().Clone()
+	if header.Get("Content-Length") == "" {
+		header.Set("Content-Length", fmt.Sprint(rec.Body.Len()))
+	}
+	header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	header.Write(w)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,273 @@
+package ossfake
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minPartSize is the size of the parts but the last one of a completed upload
+const minPartSize = 100 * 1024
+
+type upload struct {
+	key       string
+	id        string
+	header    http.Header
+	initiated time.Time
+	parts     map[int]*part
+}
+
+type part struct {
+	data         []byte
+	etag         string
+	lastModified time.Time
+}
+
+func (s *Server) initUpload(w http.ResponseWriter, r *http.Request, key string) error {
+	up := &upload{
+		key:       key,
+		id:        fmt.Sprintf("%032X", s.sequence),
+		header:    objectHeader(r),
+		initiated: s.now(),
+		parts:     make(map[int]*part),
+	}
+	s.uploads[up.id] = up
+	return writeXML(w, &struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string
+		Key      string
+		UploadId string
+	}{Bucket: s.Bucket, Key: key, UploadId: up.id})
+}
+
+func (s *Server) upload(key string, query url.Values) (*upload, error) {
+	up, ok := s.uploads[query.Get("uploadId")]
+	if !ok || up.key != key {
+		return nil, newError(http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+	}
+	return up, nil
+}
+
+func partNumber(query url.Values) (int, error) {
+	number, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || number < 1 || number > 10000 {
+		return 0, newError(http.StatusBadRequest, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive.")
+	}
+	return number, nil
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, key string, query url.Values, body []byte) error {
+	up, err := s.upload(key, query)
+	if err != nil {
+		return err
+	}
+	number, err := partNumber(query)
+	if err != nil {
+		return err
+	}
+	if err := checkMD5(r, body); err != nil {
+		return err
+	}
+	p := &part{body, etag(body), s.now()}
+	up.parts[number] = p
+	w.Header().Set("ETag", p.etag)
+	w.Header().Set("x-oss-hash-crc64ecma", crc(body))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// copyPart copies the x-oss-copy-source-range of the source, or the whole source
+// if the range is missing or malformed
+func (s *Server) copyPart(w http.ResponseWriter, r *http.Request, key string, query url.Values) error {
+	up, err := s.upload(key, query)
+	if err != nil {
+		return err
+	}
+	number, err := partNumber(query)
+	if err != nil {
+		return err
+	}
+	src, err := s.source(r)
+	if err != nil {
+		return err
+	}
+	size := int64(len(src.data))
+	first, last, ranged, err := parseRange(r.Header.Get("x-oss-copy-source-range"), size)
+	if err != nil {
+		return err
+	}
+	data := src.data
+	if ranged {
+		data = src.data[first : last+1]
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, size))
+	}
+	p := &part{data, etag(data), s.now()}
+	up.parts[number] = p
+	w.Header().Set("x-oss-hash-crc64ecma", crc(data))
+	return writeXML(w, &struct {
+		XMLName      xml.Name `xml:"CopyPartResult"`
+		LastModified string
+		ETag         string
+	}{LastModified: formatTime(p.lastModified), ETag: p.etag})
+}
+
+func (s *Server) listParts(w http.ResponseWriter, key string, query url.Values) error {
+	up, err := s.upload(key, query)
+	if err != nil {
+		return err
+	}
+	type partSummary struct {
+		PartNumber    int
+		LastModified  string
+		ETag          string
+		HashCrc64ecma string
+		Size          int
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListPartsResult"`
+		Bucket      string
+		Key         string
+		UploadId    string
+		IsTruncated bool
+		Parts       []partSummary `xml:"Part"`
+	}{Bucket: s.Bucket, Key: key, UploadId: up.id}
+	for _, number := range up.partNumbers() {
+		p := up.parts[number]
+		result.Parts = append(result.Parts, partSummary{number, formatTime(p.lastModified), p.etag, crc(p.data), len(p.data)})
+	}
+	return writeXML(w, &result)
+}
+
+func (up *upload) partNumbers() []int {
+	numbers := make([]int, 0, len(up.parts))
+	for number := range up.parts {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// completeUpload concatenates the listed parts, the ETag of the object is
+// the md5 of the part md5s followed by the number of parts
+func (s *Server) completeUpload(w http.ResponseWriter, key string, query url.Values, body []byte) error {
+	up, err := s.upload(key, query)
+	if err != nil {
+		return err
+	}
+	var request struct {
+		Parts []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	if err := readXML(body, &request); err != nil {
+		return err
+	}
+	if len(request.Parts) == 0 {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+	}
+	var data, sums []byte
+	for i, requested := range request.Parts {
+		if i > 0 && requested.PartNumber <= request.Parts[i-1].PartNumber {
+			return newError(http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order.")
+		}
+		p, ok := up.parts[requested.PartNumber]
+		if !ok || strings.Trim(requested.ETag, `"`) != strings.Trim(p.etag, `"`) {
+			return newError(http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found.")
+		}
+		if i < len(request.Parts)-1 && len(p.data) < minPartSize {
+			return newError(http.StatusBadRequest, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed size.")
+		}
+		data = append(data, p.data...)
+		sum := md5.Sum(p.data)
+		sums = append(sums, sum[:]...)
+	}
+	sum := md5.Sum(sums)
+	obj := &object{
+		data:         data,
+		header:       up.header,
+		etag:         fmt.Sprintf(`"%s-%d"`, strings.ToUpper(hex.EncodeToString(sum[:])), len(request.Parts)),
+		objectType:   "Multipart",
+		lastModified: s.now(),
+	}
+	s.objects[key] = obj
+	delete(s.uploads, up.id)
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("x-oss-hash-crc64ecma", crc(data))
+	return writeXML(w, &struct {
+		XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+		Location string
+		Bucket   string
+		Key      string
+		ETag     string
+	}{Location: s.URL + "/" + key, Bucket: s.Bucket, Key: key, ETag: obj.etag})
+}
+
+func (s *Server) abortUpload(w http.ResponseWriter, key string, query url.Values) error {
+	up, err := s.upload(key, query)
+	if err != nil {
+		return err
+	}
+	delete(s.uploads, up.id)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// listUploads lists the uploads ordered by key and initiation
+// after the key-marker and upload-id-marker
+func (s *Server) listUploads(w http.ResponseWriter, query url.Values) error {
+	prefix, keyMarker, uploadIdMarker := query.Get("prefix"), query.Get("key-marker"), query.Get("upload-id-marker")
+	max, err := maxKeys(query.Get("max-uploads"), 1000)
+	if err != nil {
+		return err
+	}
+	type uploadSummary struct {
+		Key       string
+		UploadId  string
+		Initiated string
+	}
+	result := struct {
+		XMLName            xml.Name `xml:"ListMultipartUploadsResult"`
+		Bucket             string
+		KeyMarker          string
+		UploadIdMarker     string
+		NextKeyMarker      string
+		NextUploadIdMarker string
+		MaxUploads         int
+		IsTruncated        bool
+		Uploads            []uploadSummary `xml:"Upload"`
+	}{Bucket: s.Bucket, KeyMarker: keyMarker, UploadIdMarker: uploadIdMarker, MaxUploads: max}
+
+	var uploads []*upload
+	for _, up := range s.uploads {
+		if !strings.HasPrefix(up.key, prefix) {
+			continue
+		}
+		if up.key < keyMarker || up.key == keyMarker && (uploadIdMarker == "" || up.id <= uploadIdMarker) {
+			continue
+		}
+		uploads = append(uploads, up)
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].key != uploads[j].key {
+			return uploads[i].key < uploads[j].key
+		}
+		return uploads[i].id < uploads[j].id
+	})
+	for i, up := range uploads {
+		if i == max {
+			result.IsTruncated = true
+			result.NextKeyMarker, result.NextUploadIdMarker = uploads[i-1].key, uploads[i-1].id
+			break
+		}
+		result.Uploads = append(result.Uploads, uploadSummary{up.key, up.id, formatTime(up.initiated)})
+	}
+	return writeXML(w, &result)
+}
@@ -0,0 +1,323 @@
+package ossfake
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the request headers kept with the object and returned by GET and HEAD
+var objectHeaders = []string{"Content-Type", "Cache-Control", "Content-Disposition", "Content-Encoding", "Expires", "x-oss-storage-class"}
+
+func objectHeader(r *http.Request) http.Header {
+	header := make(http.Header)
+	for _, k := range objectHeaders {
+		if v := r.Header.Get(k); v != "" {
+			header.Set(k, v)
+		}
+	}
+	for k, v := range r.Header {
+		if strings.HasPrefix(strings.ToLower(k), "x-oss-meta-") {
+			header[k] = v
+		}
+	}
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/octet-stream")
+	}
+	return header
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, key string, body []byte) error {
+	if err := checkMD5(r, body); err != nil {
+		return err
+	}
+	obj := &object{
+		data:         body,
+		header:       objectHeader(r),
+		etag:         etag(body),
+		objectType:   "Normal",
+		lastModified: s.now(),
+	}
+	s.objects[key] = obj
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("x-oss-hash-crc64ecma", crc(body))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// source returns the object of the x-oss-copy-source header
+func (s *Server) source(r *http.Request) (*object, error) {
+	source := r.Header.Get("x-oss-copy-source")
+	if strings.Contains(source, "?versionId=") {
+		return nil, newError(http.StatusNotImplemented, "NotImplemented", "The fake server doesn't support the versioning.")
+	}
+	if unescaped, err := url.PathUnescape(source); err == nil {
+		source = unescaped
+	}
+	parts := strings.SplitN(strings.TrimPrefix(source, "/"), "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, newError(http.StatusBadRequest, "InvalidArgument", "Copy Source must mention the source bucket and key.")
+	}
+	if parts[0] != s.Bucket {
+		return nil, newError(http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+	}
+	obj, ok := s.objects[parts[1]]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+	}
+	return obj, nil
+}
+
+func (s *Server) copyObject(w http.ResponseWriter, r *http.Request, key string) error {
+	src, err := s.source(r)
+	if err != nil {
+		return err
+	}
+	header := make(http.Header)
+	if strings.EqualFold(r.Header.Get("x-oss-metadata-directive"), "REPLACE") {
+		header = objectHeader(r)
+	} else {
+		for k, v := range src.header {
+			header[k] = v
+		}
+		if class := r.Header.Get("x-oss-storage-class"); class != "" {
+			header.Set("x-oss-storage-class", class)
+		}
+	}
+	obj := &object{
+		data:         src.data,
+		header:       header,
+		etag:         src.etag,
+		objectType:   src.objectType,
+		lastModified: s.now(),
+	}
+	s.objects[key] = obj
+	w.Header().Set("x-oss-hash-crc64ecma", crc(obj.data))
+	return writeXML(w, &struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		ETag         string
+		LastModified string
+	}{ETag: obj.etag, LastModified: formatTime(obj.lastModified)})
+}
+
+var rangePattern = regexp.MustCompile(`^bytes=(\d*)-(\d*)$`)
+
+// parseRange returns the first and the last byte of the range header value,
+// ok is false if the value is malformed and the whole object is returned
+func parseRange(value string, size int64) (first, last int64, ok bool, err error) {
+	match := rangePattern.FindStringSubmatch(value)
+	if match == nil || match[1] == "" && match[2] == "" {
+		return 0, 0, false, nil
+	}
+	if match[1] == "" {
+		// the suffix range "bytes=-n" of the last n bytes
+		n, _ := strconv.ParseInt(match[2], 10, 64)
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true, nil
+	}
+	first, _ = strconv.ParseInt(match[1], 10, 64)
+	last = size - 1
+	if match[2] != "" {
+		last, _ = strconv.ParseInt(match[2], 10, 64)
+		if last < first {
+			return 0, 0, false, nil
+		}
+		if last >= size {
+			last = size - 1
+		}
+	}
+	if first >= size {
+		return 0, 0, false, newError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range cannot be satisfied.")
+	}
+	return first, last, true, nil
+}
+
+// the response-* query parameters override the response headers
+var responseOverrides = map[string]string{
+	"response-content-type":        "Content-Type",
+	"response-content-language":    "Content-Language",
+	"response-expires":             "Expires",
+	"response-cache-control":       "Cache-Control",
+	"response-content-disposition": "Content-Disposition",
+	"response-content-encoding":    "Content-Encoding",
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, key string, query url.Values) error {
+	obj, ok := s.objects[key]
+	if !ok {
+		return newError(http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+	}
+	size := int64(len(obj.data))
+	first, last, ranged, err := parseRange(r.Header.Get("Range"), size)
+	if err != nil {
+		return err
+	}
+	header := w.Header()
+	for k, v := range obj.header {
+		header[k] = v
+	}
+	for param, k := range responseOverrides {
+		if v := query.Get(param); v != "" {
+			header.Set(k, v)
+		}
+	}
+	if header.Get("x-oss-storage-class") == "" {
+		header.Set("x-oss-storage-class", "Standard")
+	}
+	header.Set("ETag", obj.etag)
+	header.Set("Last-Modified", obj.lastModified.UTC().Format(http.TimeFormat))
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("x-oss-object-type", obj.objectType)
+	header.Set("x-oss-hash-crc64ecma", crc(obj.data))
+	data, status := obj.data, http.StatusOK
+	if ranged {
+		data, status = obj.data[first:last+1], http.StatusPartialContent
+		header.Set("Content-Range", "bytes "+strconv.FormatInt(first, 10)+"-"+strconv.FormatInt(last, 10)+"/"+strconv.FormatInt(size, 10))
+	}
+	header.Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method != "HEAD" {
+		w.Write(data)
+	}
+	return nil
+}
+
+type objectSummary struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Type         string
+	Size         int64
+	StorageClass string
+}
+
+type commonPrefix struct {
+	Prefix string
+}
+
+// listObjects lists the keys after the marker, the keys containing the delimiter
+// after the prefix are rolled up into a common prefix
+func (s *Server) listObjects(w http.ResponseWriter, query url.Values) error {
+	prefix, delimiter, marker := query.Get("prefix"), query.Get("delimiter"), query.Get("marker")
+	max, err := maxKeys(query.Get("max-keys"), 100)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Name           string
+		Prefix         string
+		Marker         string
+		MaxKeys        int
+		Delimiter      string
+		IsTruncated    bool
+		NextMarker     string `xml:",omitempty"`
+		Contents       []objectSummary
+		CommonPrefixes []commonPrefix
+	}{Name: s.Bucket, Prefix: prefix, Marker: marker, MaxKeys: max, Delimiter: delimiter}
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	count, last := 0, ""
+	for _, key := range keys {
+		if key <= marker || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rolledUp := ""
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				rolledUp = key[:len(prefix)+i+len(delimiter)]
+				if rolledUp == last || rolledUp <= marker {
+					continue
+				}
+			}
+		}
+		if count == max {
+			result.IsTruncated = true
+			result.NextMarker = last
+			break
+		}
+		count++
+		if rolledUp != "" {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{rolledUp})
+			last = rolledUp
+			continue
+		}
+		obj := s.objects[key]
+		class := obj.header.Get("x-oss-storage-class")
+		if class == "" {
+			class = "Standard"
+		}
+		result.Contents = append(result.Contents, objectSummary{
+			Key:          key,
+			LastModified: formatTime(obj.lastModified),
+			ETag:         obj.etag,
+			Type:         obj.objectType,
+			Size:         int64(len(obj.data)),
+			StorageClass: class,
+		})
+		last = key
+	}
+	return writeXML(w, &result)
+}
+
+func maxKeys(value string, defaultMax int) (int, error) {
+	if value == "" {
+		return defaultMax, nil
+	}
+	max, err := strconv.Atoi(value)
+	if err != nil || max < 1 || max > 1000 {
+		return 0, newError(http.StatusBadRequest, "InvalidArgument", "The max keys must be between 1 and 1000.")
+	}
+	return max, nil
+}
+
+func (s *Server) deleteObjects(w http.ResponseWriter, r *http.Request, body []byte) error {
+	if r.Header.Get("Content-MD5") == "" {
+		return newError(http.StatusBadRequest, "InvalidDigest", "Missing required header for this request: Content-MD5.")
+	}
+	if err := checkMD5(r, body); err != nil {
+		return err
+	}
+	var request struct {
+		Quiet   bool
+		Objects []struct {
+			Key       string
+			VersionId string
+		} `xml:"Object"`
+	}
+	if err := readXML(body, &request); err != nil {
+		return err
+	}
+	if len(request.Objects) > 1000 {
+		return newError(http.StatusBadRequest, "MalformedXML", "The number of the objects exceeds 1000.")
+	}
+	type deleted struct {
+		Key string
+	}
+	var result struct {
+		XMLName xml.Name  `xml:"DeleteResult"`
+		Deleted []deleted `xml:"Deleted"`
+	}
+	for _, obj := range request.Objects {
+		if obj.VersionId != "" {
+			return newError(http.StatusNotImplemented, "NotImplemented", "The fake server doesn't support the versioning.")
+		}
+	}
+	for _, obj := range request.Objects {
+		delete(s.objects, obj.Key)
+		if !request.Quiet {
+			result.Deleted = append(result.Deleted, deleted{obj.Key})
+		}
+	}
+	return writeXML(w, &result)
+}
@@ -0,0 +1,266 @@
+// Package ossfake is an in-memory OSS bucket served by an httptest.Server,
+// it lets the tests of the oss package run without a real bucket.
+// It doesn't import the oss package and checks the requests against the oss api
+// documents rather than the sdk code, only the signed sub resources are shared.
+package ossfake
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server serves a single bucket whose requests must be signed
+// by the signature version 1 or 4 with AccessKeyId and AccessKeySecret
+type Server struct {
+	*httptest.Server
+	Bucket          string
+	AccessKeyId     string
+	AccessKeySecret string
+	Region          string // the region of the signature version 4 scope, any region when empty
+
+	mu       sync.Mutex
+	objects  map[string]*object
+	uploads  map[string]*upload
+	sequence int
+	now      func() time.Time
+	faults   []*Fault
+}
+
+type object struct {
+	data         []byte
+	header       http.Header // the content and the user metadata headers
+	etag         string
+	objectType   string
+	lastModified time.Time
+}
+
+// NewServer starts the server, the caller should Close it
+func NewServer(bucket, accessKeyId, accessKeySecret string) *Server {
+	s := &Server{
+		Bucket:          bucket,
+		AccessKeyId:     accessKeyId,
+		AccessKeySecret: accessKeySecret,
+		objects:         make(map[string]*object),
+		uploads:         make(map[string]*upload),
+		now:             time.Now,
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Object returns a copy of the content and the metadata headers of the object key
+func (s *Server) Object(key string) ([]byte, http.Header, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, nil, false
+	}
+	return append([]byte(nil), obj.data...), obj.header.Clone(), true
+}
+
+// SetObject stores the object key as if it was uploaded with the header,
+// e.g. to tamper with an object
+func (s *Server) SetObject(key string, data []byte, header http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if header == nil {
+		header = http.Header{"Content-Type": {"application/octet-stream"}}
+	}
+	s.objects[key] = &object{
+		data:         append([]byte(nil), data...),
+		header:       header.Clone(),
+		etag:         etag(data),
+		objectType:   "Normal",
+		lastModified: s.now(),
+	}
+}
+
+// ossError is the error document of a failed request
+type ossError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *ossError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.status, e.code, e.message)
+}
+
+func newError(status int, code, message string) *ossError {
+	return &ossError{status, code, message}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	f := s.fault(r)
+	if f != nil && f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	corruptRequest := f != nil && f.Corrupt && len(body) > 0
+	if corruptRequest {
+		corrupt(body)
+	}
+	rec := httptest.NewRecorder()
+	s.serve(rec, r, body, f)
+	if f != nil && f.Corrupt && !corruptRequest {
+		corrupt(rec.Body.Bytes())
+	}
+	deliver(w, rec, f)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, body []byte, f *Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sequence++
+	requestId := fmt.Sprintf("%024X", s.sequence)
+	w.Header().Set("x-oss-request-id", requestId)
+	if f != nil && f.Status != 0 {
+		writeError(w, r, newError(f.Status, f.Code, f.Message), requestId)
+		return
+	}
+	if err := s.handle(w, r, body); err != nil {
+		writeError(w, r, err, requestId)
+	}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request, body []byte) error {
+	if err := s.authenticate(r); err != nil {
+		return err
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	has := func(param string) bool {
+		_, ok := query[param]
+		return ok
+	}
+	copySource := r.Header.Get("x-oss-copy-source")
+	switch {
+	case key == "" && r.Method == "GET" && has("uploads"):
+		return s.listUploads(w, query)
+	case key == "" && r.Method == "GET":
+		return s.listObjects(w, query)
+	case key == "" && r.Method == "POST" && has("delete"):
+		return s.deleteObjects(w, r, body)
+	case key == "":
+		return newError(http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource.")
+	case r.Method == "POST" && has("uploads"):
+		return s.initUpload(w, r, key)
+	case r.Method == "PUT" && has("uploadId") && copySource != "":
+		return s.copyPart(w, r, key, query)
+	case r.Method == "PUT" && has("uploadId"):
+		return s.uploadPart(w, r, key, query, body)
+	case r.Method == "GET" && has("uploadId"):
+		return s.listParts(w, key, query)
+	case r.Method == "POST" && has("uploadId"):
+		return s.completeUpload(w, key, query, body)
+	case r.Method == "DELETE" && has("uploadId"):
+		return s.abortUpload(w, key, query)
+	case r.Method == "PUT" && copySource != "":
+		return s.copyObject(w, r, key)
+	case r.Method == "PUT":
+		return s.putObject(w, r, key, body)
+	case r.Method == "GET" || r.Method == "HEAD":
+		return s.getObject(w, r, key, query)
+	case r.Method == "DELETE":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return newError(http.StatusNotImplemented, "NotImplemented", "The fake server doesn't implement "+r.Method+" "+r.URL.RawQuery)
+}
+
+// writeError writes the error document, or its base64 in the x-oss-err header
+// of a HEAD response which has no body
+func writeError(w http.ResponseWriter, r *http.Request, err error, requestId string) {
+	e, ok := err.(*ossError)
+	if !ok {
+		e = newError(http.StatusInternalServerError, "InternalError", err.Error())
+	}
+	data, _ := xml.Marshal(&struct {
+		XMLName   xml.Name `xml:"Error"`
+		Code      string
+		Message   string
+		RequestId string
+		HostId    string
+	}{Code: e.code, Message: e.message, RequestId: requestId, HostId: r.Host})
+	if r.Method == "HEAD" {
+		w.Header().Set("x-oss-err", base64.StdEncoding.EncodeToString(data))
+		w.WriteHeader(e.status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(data)))
+	w.WriteHeader(e.status)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) error {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+	return nil
+}
+
+func readXML(body []byte, v interface{}) error {
+	if err := xml.Unmarshal(body, v); err != nil {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+	}
+	return nil
+}
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+func crc(data []byte) string {
+	return strconv.FormatUint(crc64.Checksum(data, crcTable), 10)
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + strings.ToUpper(hex.EncodeToString(sum[:])) + `"`
+}
+
+// checkMD5 compares the Content-MD5 header, if any, with body
+func checkMD5(r *http.Request, body []byte) error {
+	value := r.Header.Get("Content-MD5")
+	if value == "" {
+		return nil
+	}
+	expected, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(expected) != md5.Size {
+		return newError(http.StatusBadRequest, "InvalidDigest", "The Content-MD5 you specified was invalid.")
+	}
+	if sum := md5.Sum(body); string(sum[:]) != string(expected) {
+		return newError(http.StatusBadRequest, "BadDigest", "The Content-MD5 you specified did not match what we received.")
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
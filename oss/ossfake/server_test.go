@@ -0,0 +1,178 @@
+package ossfake
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRequest(t *testing.T, s *Server, method, path string, body []byte) *http.Request {
+	r, err := http.NewRequest(method, s.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	return r
+}
+
+// do signs r with the credentials of the server
+func do(t *testing.T, s *Server, r *http.Request) (*http.Response, []byte) {
+	if r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", "OSS "+s.AccessKeyId+":"+sign(s.AccessKeySecret, stringToSign(r, s.Bucket, r.Header.Get("Date"))))
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp, body
+}
+
+func errorCode(body []byte) string {
+	var doc struct {
+		Code string
+	}
+	xml.Unmarshal(body, &doc)
+	return doc.Code
+}
+
+func TestAuthentication(t *testing.T) {
+	s := NewServer("bucket", "ak", "sk")
+	defer s.Close()
+	r := newRequest(t, s, "PUT", "/a.txt", []byte("hello"))
+	r.Header.Set("Authorization", "OSS ak:bad")
+	if resp, body := do(t, s, r); resp.StatusCode != http.StatusForbidden || errorCode(body) != "SignatureDoesNotMatch" {
+		t.Errorf("the bad signature should be rejected %d %s", resp.StatusCode, body)
+	}
+	r = newRequest(t, s, "GET", "/a.txt", nil)
+	r.Header.Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	if resp, body := do(t, s, r); errorCode(body) != "RequestTimeTooSkewed" {
+		t.Errorf("the old request should be rejected %d %s", resp.StatusCode, body)
+	}
+	r = newRequest(t, s, "PUT", "/a.txt?acl", []byte("hello"))
+	r.Header.Set("x-oss-meta-b", "2")
+	r.Header.Set("x-oss-meta-a", "1")
+	if resp, body := do(t, s, r); resp.StatusCode == http.StatusForbidden {
+		t.Errorf("the signed request should be accepted %s", body)
+	}
+}
+
+func TestObjectRange(t *testing.T) {
+	s := NewServer("bucket", "ak", "sk")
+	defer s.Close()
+	do(t, s, newRequest(t, s, "PUT", "/a.txt", []byte("0123456789")))
+	for value, expected := range map[string]string{
+		"bytes=2-4":   "234",
+		"bytes=7-":    "789",
+		"bytes=-2":    "89",
+		"bytes=8-100": "89",
+		"bytes=5-1":   "0123456789",
+	} {
+		r := newRequest(t, s, "GET", "/a.txt", nil)
+		r.Header.Set("Range", value)
+		if _, body := do(t, s, r); string(body) != expected {
+			t.Errorf("wrong range %s expected %s, actual %s", value, expected, body)
+		}
+	}
+	r := newRequest(t, s, "GET", "/a.txt", nil)
+	r.Header.Set("Range", "bytes=10-")
+	if resp, _ := do(t, s, r); resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("the range should not be satisfiable %d", resp.StatusCode)
+	}
+	resp, body := do(t, s, newRequest(t, s, "HEAD", "/missing", nil))
+	if resp.StatusCode != http.StatusNotFound || len(body) != 0 || resp.Header.Get("x-oss-err") == "" {
+		t.Errorf("wrong head of the missing object %d %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	s := NewServer("bucket", "ak", "sk")
+	defer s.Close()
+	for _, key := range []string{"a/1", "a/b/2", "a/b/3", "a/c/4", "a/5", "b"} {
+		do(t, s, newRequest(t, s, "PUT", "/"+key, nil))
+	}
+	var entries []string
+	marker := ""
+	for {
+		_, body := do(t, s, newRequest(t, s, "GET", "/?prefix=a%2F&delimiter=%2F&max-keys=2&marker="+marker, nil))
+		var result struct {
+			IsTruncated    bool
+			NextMarker     string
+			Contents       []struct{ Key string }
+			CommonPrefixes []struct{ Prefix string }
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			t.Fatalf("cant parse %s", body)
+		}
+		for _, c := range result.Contents {
+			entries = append(entries, c.Key)
+		}
+		for _, p := range result.CommonPrefixes {
+			entries = append(entries, p.Prefix)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextMarker
+	}
+	if strings.Join(entries, ",") != "a/1,a/5,a/b/,a/c/" {
+		t.Errorf("wrong entries %v", entries)
+	}
+}
+
+func TestCompleteUpload(t *testing.T) {
+	s := NewServer("bucket", "ak", "sk")
+	defer s.Close()
+	_, body := do(t, s, newRequest(t, s, "POST", "/big?uploads", nil))
+	var initiated struct {
+		UploadId string
+	}
+	xml.Unmarshal(body, &initiated)
+	resp, _ := do(t, s, newRequest(t, s, "PUT", "/big?partNumber=1&uploadId="+initiated.UploadId, []byte("small")))
+	etag := resp.Header.Get("ETag")
+	do(t, s, newRequest(t, s, "PUT", "/big?partNumber=2&uploadId="+initiated.UploadId, []byte("last")))
+	complete := "<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>" + etag + "</ETag></Part></CompleteMultipartUpload>"
+	if _, body := do(t, s, newRequest(t, s, "POST", "/big?uploadId="+initiated.UploadId, []byte(complete))); errorCode(body) != "" {
+		t.Errorf("the single small part should complete %s", body)
+	}
+	if data, _, ok := s.Object("big"); !ok || string(data) != "small" {
+		t.Errorf("wrong completed object %s", data)
+	}
+	if _, body := do(t, s, newRequest(t, s, "DELETE", "/big?uploadId="+initiated.UploadId, nil)); errorCode(body) != "NoSuchUpload" {
+		t.Errorf("the completed upload should be removed %s", body)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s := NewServer("bucket", "ak", "sk")
+	defer s.Close()
+	s.Inject(Fault{Method: "PUT", Key: "a.txt", Times: 2, Status: http.StatusServiceUnavailable, Code: "ServiceUnavailable"})
+	for i := 0; i < 2; i++ {
+		if _, body := do(t, s, newRequest(t, s, "PUT", "/a.txt", []byte("hello"))); errorCode(body) != "ServiceUnavailable" {
+			t.Errorf("the put %d should fail %s", i, body)
+		}
+	}
+	if _, body := do(t, s, newRequest(t, s, "PUT", "/a.txt", []byte("hello"))); errorCode(body) != "" {
+		t.Errorf("the fault should be exhausted %s", body)
+	}
+
+	s.Inject(Fault{SubResource: "acl", Corrupt: true})
+	r := newRequest(t, s, "GET", "/a.txt?acl", nil)
+	if _, body := do(t, s, newRequest(t, s, "GET", "/a.txt", nil)); string(body) != "hello" {
+		t.Errorf("the fault should not match %s", body)
+	}
+	if _, body := do(t, s, r); string(body) == "hello" {
+		t.Errorf("the body should be corrupted %s", body)
+	}
+	s.ClearFaults()
+
+	s.SetClockSkew(20 * time.Minute)
+	if _, body := do(t, s, newRequest(t, s, "GET", "/a.txt", nil)); errorCode(body) != "RequestTimeTooSkewed" {
+		t.Errorf("the request should be too skewed %s", body)
+	}
+}
@@ -0,0 +1,252 @@
+package ossfake
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/topikachu/oss-mini-go-sdk/oss/internal/signing"
+)
+
+// maxSkew is the difference allowed between the Date of a request and the server clock
+const maxSkew = 15 * time.Minute
+
+const (
+	algorithmV4  = "OSS4-HMAC-SHA256"
+	timeFormatV4 = "20060102T150405Z"
+	terminatorV4 = "aliyun_v4_request"
+)
+
+// authenticate checks the Authorization header or the signature of a presigned url
+func (s *Server) authenticate(r *http.Request) error {
+	query := r.URL.Query()
+	var accessKeyId, signature, date string
+	if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, algorithmV4+" ") {
+		return s.authenticateV4(r, authorization)
+	} else if query.Get("x-oss-signature-version") == algorithmV4 {
+		return s.authenticateV4(r, "")
+	} else if authorization != "" {
+		if !strings.HasPrefix(authorization, "OSS ") {
+			return newError(http.StatusBadRequest, "InvalidArgument", "Authorization header is invalid.")
+		}
+		credential := strings.SplitN(strings.TrimPrefix(authorization, "OSS "), ":", 2)
+		if len(credential) != 2 {
+			return newError(http.StatusBadRequest, "InvalidArgument", "Authorization header is invalid.")
+		}
+		accessKeyId, signature = credential[0], credential[1]
+		date = r.Header.Get("Date")
+		t, err := http.ParseTime(date)
+		if err != nil {
+			return newError(http.StatusForbidden, "AccessDenied", "Invalid date format.")
+		}
+		if skew := s.now().Sub(t); skew > maxSkew || skew < -maxSkew {
+			return newError(http.StatusForbidden, "RequestTimeTooSkewed", "The difference between the request time and the current time is too large.")
+		}
+	} else if query.Get("Signature") != "" {
+		accessKeyId, signature = query.Get("OSSAccessKeyId"), query.Get("Signature")
+		date = query.Get("Expires")
+		expires, err := strconv.ParseInt(date, 10, 64)
+		if err != nil || s.now().Unix() > expires {
+			return newError(http.StatusForbidden, "AccessDenied", "Request has expired.")
+		}
+	} else {
+		return newError(http.StatusForbidden, "AccessDenied", "You have no right to access this object because of bucket acl.")
+	}
+	if accessKeyId != s.AccessKeyId {
+		return newError(http.StatusForbidden, "InvalidAccessKeyId", "The OSS Access Key Id you provided does not exist in our records.")
+	}
+	expected := sign(s.AccessKeySecret, stringToSign(r, s.Bucket, date))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return newError(http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.")
+	}
+	return nil
+}
+
+// stringToSign is the VERB, Content-MD5, Content-Type, Date,
+// the sorted x-oss-* headers and the canonical resource of r
+func stringToSign(r *http.Request, bucket, date string) string {
+	headers := make(map[string]string)
+	var keys []string
+	for k, v := range r.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-oss-") {
+			headers[k] = strings.Join(v, ",")
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(r.Method + "\n")
+	b.WriteString(r.Header.Get("Content-MD5") + "\n")
+	b.WriteString(r.Header.Get("Content-Type") + "\n")
+	b.WriteString(date + "\n")
+	for _, k := range keys {
+		b.WriteString(k + ":" + headers[k] + "\n")
+	}
+
+	var resources []string
+	for k, v := range r.URL.Query() {
+		if !signing.SubResources[k] {
+			continue
+		}
+		for _, vi := range v {
+			if vi == "" {
+				resources = append(resources, k)
+			} else {
+				resources = append(resources, k+"="+vi)
+			}
+		}
+	}
+	sort.Strings(resources)
+	b.WriteString("/" + bucket + r.URL.Path)
+	if len(resources) > 0 {
+		b.WriteString("?" + strings.Join(resources, "&"))
+	}
+	return b.String()
+}
+
+func sign(accessKeySecret, stringToSign string) string {
+	hash := hmac.New(sha1.New, []byte(accessKeySecret))
+	hash.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// authenticateV4 checks the OSS4-HMAC-SHA256 authorization, or the x-oss-* query
+// parameters of a presigned url when authorization is empty
+func (s *Server) authenticateV4(r *http.Request, authorization string) error {
+	query := r.URL.Query()
+	var credential, additionalHeaders, signature, date string
+	if authorization != "" {
+		for _, field := range strings.Split(strings.TrimPrefix(authorization, algorithmV4+" "), ",") {
+			kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+			if len(kv) != 2 {
+				return newError(http.StatusBadRequest, "InvalidArgument", "Authorization header is invalid.")
+			}
+			switch kv[0] {
+			case "Credential":
+				credential = kv[1]
+			case "AdditionalHeaders":
+				additionalHeaders = kv[1]
+			case "Signature":
+				signature = kv[1]
+			}
+		}
+		date = r.Header.Get("x-oss-date")
+	} else {
+		credential = query.Get("x-oss-credential")
+		additionalHeaders = query.Get("x-oss-additional-headers")
+		signature = query.Get("x-oss-signature")
+		date = query.Get("x-oss-date")
+	}
+	t, err := time.Parse(timeFormatV4, date)
+	if err != nil {
+		return newError(http.StatusForbidden, "AccessDenied", "Invalid date format.")
+	}
+	if authorization != "" {
+		if skew := s.now().Sub(t); skew > maxSkew || skew < -maxSkew {
+			return newError(http.StatusForbidden, "RequestTimeTooSkewed", "The difference between the request time and the current time is too large.")
+		}
+	} else {
+		expires, err := strconv.ParseInt(query.Get("x-oss-expires"), 10, 64)
+		if err != nil || s.now().After(t.Add(time.Duration(expires)*time.Second)) {
+			return newError(http.StatusForbidden, "AccessDenied", "Request has expired.")
+		}
+	}
+
+	// the credential is accessKeyId/date/region/oss/aliyun_v4_request
+	scope := strings.SplitN(credential, "/", 2)
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[3] != "oss" || parts[4] != terminatorV4 {
+		return newError(http.StatusBadRequest, "InvalidArgument", "Credential is invalid.")
+	}
+	if parts[0] != s.AccessKeyId {
+		return newError(http.StatusForbidden, "InvalidAccessKeyId", "The OSS Access Key Id you provided does not exist in our records.")
+	}
+	if parts[1] != t.Format("20060102") || (s.Region != "" && parts[2] != s.Region) {
+		return newError(http.StatusForbidden, "SignatureDoesNotMatch", "The credential scope is invalid.")
+	}
+
+	var signed []string
+	if additionalHeaders != "" {
+		signed = strings.Split(additionalHeaders, ";")
+	}
+	canonicalRequest := canonicalRequestV4(r, s.Bucket, signed)
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := algorithmV4 + "\n" + date + "\n" + scope[1] + "\n" + hex.EncodeToString(hashedRequest[:])
+
+	key := hmacSHA256([]byte("aliyun_v4"+s.AccessKeySecret), parts[1])
+	for _, k := range parts[2:] {
+		key = hmacSHA256(key, k)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return newError(http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.")
+	}
+	return nil
+}
+
+// canonicalRequestV4 is the VERB, the canonical uri, query and headers,
+// the additional headers and the hashed payload of r
+func canonicalRequestV4(r *http.Request, bucket string, additionalHeaders []string) string {
+	canonicalUri := "/" + bucket + "/" + strings.Replace(escapeV4(strings.TrimPrefix(r.URL.Path, "/")), "%2F", "/", -1)
+
+	var queries []string
+	for k, v := range r.URL.Query() {
+		if k == "x-oss-signature" {
+			continue
+		}
+		for _, vi := range v {
+			if vi == "" {
+				queries = append(queries, escapeV4(k))
+			} else {
+				queries = append(queries, escapeV4(k)+"="+escapeV4(vi))
+			}
+		}
+	}
+	sort.Strings(queries)
+
+	var headers []string
+	for k, v := range r.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || k == "content-md5" || strings.HasPrefix(k, "x-oss-") {
+			headers = append(headers, k+":"+strings.TrimSpace(strings.Join(v, ","))+"\n")
+		}
+	}
+	for _, h := range additionalHeaders {
+		if h == "host" {
+			headers = append(headers, h+":"+r.Host+"\n")
+		} else if v, ok := r.Header[http.CanonicalHeaderKey(h)]; ok {
+			headers = append(headers, h+":"+strings.TrimSpace(strings.Join(v, ","))+"\n")
+		}
+	}
+	sort.Strings(headers)
+
+	hashedPayload := r.Header.Get("x-oss-content-sha256")
+	if hashedPayload == "" {
+		hashedPayload = "UNSIGNED-PAYLOAD"
+	}
+	return r.Method + "\n" +
+		canonicalUri + "\n" +
+		strings.Join(queries, "&") + "\n" +
+		strings.Join(headers, "") + "\n" +
+		strings.Join(additionalHeaders, ";") + "\n" +
+		hashedPayload
+}
+
+func escapeV4(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	hash := hmac.New(sha256.New, key)
+	hash.Write([]byte(data))
+	return hash.Sum(nil)
+}
@@ -0,0 +1,137 @@
+package ossrecord
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Recorder is a http.RoundTripper recording the interactions sent by Transport,
+// call Save once the interactions are done
+type Recorder struct {
+	Transport http.RoundTripper // http.DefaultTransport if nil
+
+	path     string
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder records the interactions to the cassette file path
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport, path: path}
+}
+
+func (recorder *Recorder) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	forward := r.Clone(r.Context())
+	if r.Body != nil {
+		forward.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	transport := recorder.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(forward)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, &Interaction{
+		Request: newRequest(r, body),
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     scrubHeader(resp.Header),
+			Body:       responseBody,
+		},
+	})
+	return resp, nil
+}
+
+// Save writes the interactions recorded so far
+func (recorder *Recorder) Save() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.cassette.Save(recorder.path)
+}
+
+// Replayer is a http.RoundTripper responding the recorded interactions,
+// a request gets the response of the first unused interaction it matches
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer replays the cassette file path
+func NewReplayer(path string) (*Replayer, error) {
+	cassette, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{cassette: cassette, used: make([]bool, len(cassette.Interactions))}, nil
+}
+
+func (replayer *Replayer) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	request := newRequest(r, body)
+
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+	for i, interaction := range replayer.cassette.Interactions {
+		if replayer.used[i] || !interaction.Request.matches(&request) {
+			continue
+		}
+		replayer.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       r,
+		}, nil
+	}
+	return nil, fmt.Errorf("ossrecord: no recorded interaction for %s %s?%s", r.Method, r.URL.Path, strings.Join(request.SubResources, "&"))
+}
+
+// Unused returns the number of the interactions not replayed yet
+func (replayer *Replayer) Unused() int {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+	unused := 0
+	for _, used := range replayer.used {
+		if !used {
+			unused++
+		}
+	}
+	return unused
+}
+
+// readBody reads and closes the body of r
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	defer r.Body.Close()
+	return ioutil.ReadAll(r.Body)
+}
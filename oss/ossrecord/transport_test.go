@@ -0,0 +1,71 @@
+package ossrecord
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("x-oss-request-id", strings.Repeat("0", count))
+		w.Write(append([]byte(r.Method+" "), body...))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder := NewRecorder(path, nil)
+	client := &http.Client{Transport: recorder}
+
+	send := func(client *http.Client, method, query, body string) (string, error) {
+		r, _ := http.NewRequest(method, server.URL+"/a.txt"+query, bytes.NewReader([]byte(body)))
+		r.Header.Set("Authorization", "OSS ak:signature")
+		r.Header.Set("x-oss-security-token", "token")
+		resp, err := client.Do(r)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		return resp.Header.Get("x-oss-request-id") + " " + string(data), nil
+	}
+	first, _ := send(client, "PUT", "?acl&Signature=secret&Expires=1", "hello")
+	second, _ := send(client, "GET", "", "")
+	third, _ := send(client, "GET", "", "")
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("cant save %v", err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	for _, secret := range []string{"ak:signature", "token", "secret"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("the cassette should not contain %s", secret)
+		}
+	}
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("cant load %v", err)
+	}
+	client = &http.Client{Transport: replayer}
+	if replayed, err := send(client, "PUT", "?acl&Signature=other&Expires=2", "hello"); err != nil || replayed != first {
+		t.Errorf("wrong replay expected %s, actual %s %v", first, replayed, err)
+	}
+	if replayed, _ := send(client, "GET", "", ""); replayed != second {
+		t.Errorf("wrong replay expected %s, actual %s", second, replayed)
+	}
+	if replayed, _ := send(client, "GET", "", ""); replayed != third {
+		t.Errorf("the interactions should be replayed in order expected %s, actual %s", third, replayed)
+	}
+	if _, err := send(client, "PUT", "?acl", "changed"); err == nil {
+		t.Errorf("a different body should not match")
+	}
+	if replayer.Unused() != 0 || count != 3 {
+		t.Errorf("wrong interactions %d unused, %d sent", replayer.Unused(), count)
+	}
+}